@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"gin-example/service"
+)
+
+var appKeyCmd = &cobra.Command{
+	Use:   "appkey",
+	Short: "Manage AppKey and AppSecret used by POST /auth",
+}
+
+var appKeyCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Generate and save a random AppKey and AppSecret",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupDatabase(); err != nil {
+			return err
+		}
+		appKey, appSecret, err := service.CreateAuth(cmd.Context())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "appKey: %s\nappSecret: %s\n", appKey, appSecret)
+		return nil
+	},
+}
+
+func init() {
+	appKeyCmd.AddCommand(appKeyCreateCmd)
+	rootCmd.AddCommand(appKeyCmd)
+}
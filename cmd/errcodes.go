@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"gin-example/pkg/errcode"
+)
+
+var errcodesFormat string
+
+var errcodesCmd = &cobra.Command{
+	Use:   "errcodes",
+	Short: "Error codes returned by the API",
+}
+
+// errcodesExportCmd 导出错误码, 不需要读取配置
+var errcodesExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export all error codes as json, csv or markdown",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		eMsgs := errcode.GetAllErrorMessage()
+		out := cmd.OutOrStdout()
+
+		switch errcodesFormat {
+		case "json":
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			encoder.SetEscapeHTML(false)
+			return encoder.Encode(eMsgs)
+		case "csv":
+			w := csv.NewWriter(out)
+			_ = w.Write([]string{"code", "message"})
+			for _, eMsg := range eMsgs {
+				_ = w.Write([]string{eMsg.Code, eMsg.Message})
+			}
+			w.Flush()
+			return w.Error()
+		case "markdown":
+			fmt.Fprintln(out, "| Code | Message |")
+			fmt.Fprintln(out, "| --- | --- |")
+			for _, eMsg := range eMsgs {
+				fmt.Fprintf(out, "| %s | %s |\n", eMsg.Code, strings.ReplaceAll(eMsg.Message, "|", "\\|"))
+			}
+			return nil
+		default:
+			return errors.Errorf("unknown format %s, use json|csv|markdown", errcodesFormat)
+		}
+	},
+}
+
+func init() {
+	errcodesExportCmd.Flags().StringVar(&errcodesFormat, "format", "json", "output format, json|csv|markdown")
+	errcodesCmd.AddCommand(errcodesExportCmd)
+	rootCmd.AddCommand(errcodesCmd)
+}
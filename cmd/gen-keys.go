@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"gin-example/pkg/secure-cookie"
+)
+
+var (
+	hashKeyLength  int
+	blockKeyLength int
+)
+
+// genKeysCmd 生成 Session.KeyPairs 使用的密钥, 不需要读取配置
+var genKeysCmd = &cobra.Command{
+	Use:   "gen-keys",
+	Short: "Generate a secure cookie hash key and block key for Session.KeyPairs",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch blockKeyLength {
+		case 0, 16, 24, 32:
+		default:
+			return errors.New("block key length must be 0, 16, 24 or 32")
+		}
+
+		hashKey := securecookie.GenerateRandomKey(hashKeyLength)
+		if hashKey == nil {
+			return errors.New("generate hash key failed")
+		}
+		blockKey := ""
+		if blockKeyLength > 0 {
+			key := securecookie.GenerateRandomKey(blockKeyLength)
+			if key == nil {
+				return errors.New("generate block key failed")
+			}
+			blockKey = base64.StdEncoding.EncodeToString(key)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "  KeyPairs:\n    - HashKey: %s\n      BlockKey: %s\n",
+			base64.StdEncoding.EncodeToString(hashKey), blockKey)
+		return nil
+	},
+}
+
+func init() {
+	genKeysCmd.Flags().IntVar(&hashKeyLength, "hash-key-length", 64, "hash key length in bytes, 32 or 64 is recommended")
+	genKeysCmd.Flags().IntVar(&blockKeyLength, "block-key-length", 32, "block key length in bytes, 16, 24 or 32 for AES-128, AES-192, AES-256, 0 to disable encryption")
+	rootCmd.AddCommand(genKeysCmd)
+}
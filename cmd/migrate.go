@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"gin-example/models"
+	"gin-example/pkg/migrate"
+)
+
+var (
+	migrateDryRun bool
+	migrateTo     uint
+	migrateSteps  int
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage the database schema with versioned migrations",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply pending migrations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		migrator, err := newMigrator(cmd)
+		if err != nil {
+			return err
+		}
+		return migrator.Up(cmd.Context(), migrateTo)
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Roll back the latest applied migrations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		migrator, err := newMigrator(cmd)
+		if err != nil {
+			return err
+		}
+		return migrator.Down(cmd.Context(), migrateSteps)
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show applied and pending migrations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		migrator, err := newMigrator(cmd)
+		if err != nil {
+			return err
+		}
+		statuses, err := migrator.Status(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := ""
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, status.Status, appliedAt)
+		}
+		return w.Flush()
+	},
+}
+
+func newMigrator(cmd *cobra.Command) (*migrate.Migrator, error) {
+	if err := setupDatabase(); err != nil {
+		return nil, err
+	}
+	return models.NewMigrator(migrate.Options{
+		DryRun: migrateDryRun,
+		Out:    cmd.OutOrStdout(),
+	})
+}
+
+func init() {
+	migrateCmd.PersistentFlags().BoolVar(&migrateDryRun, "dry-run", false, "print the SQL without executing it")
+	migrateUpCmd.Flags().UintVar(&migrateTo, "to", 0, "migrate up to this version, 0 for the latest")
+	migrateDownCmd.Flags().IntVar(&migrateSteps, "steps", 1, "number of migrations to roll back")
+
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"gin-example/pkg/cache"
+	"gin-example/pkg/database"
+	"gin-example/pkg/logging"
+	"gin-example/pkg/setting"
+)
+
+var (
+	configFile  string
+	profile     string
+	printConfig bool
+)
+
+// rootCmd 不带子命令时等同于 serve
+var rootCmd = &cobra.Command{
+	Use:          "gin-example",
+	Short:        "smp ops system",
+	SilenceUsage: true,
+	RunE:         runServe,
+}
+
+func init() {
+	flags := rootCmd.PersistentFlags()
+	flags.StringVar(&configFile, "config", os.Getenv("GINEX_CONFIG"), "config file (default "+setting.DefaultConfigFile+")")
+	flags.StringVar(&profile, "profile", os.Getenv("GINEX_PROFILE"), "config profile, load config.<profile>.yaml over the config file")
+	flags.BoolVar(&printConfig, "print-config", false, "print the effective config with secrets masked and exit")
+}
+
+// Execute 执行命令行, 每个子命令只初始化自己需要的模块
+func Execute() {
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// setupConfig 初始化配置, 指定 --print-config 时输出配置后退出
+func setupConfig() error {
+	if err := setting.Setup(configFile, profile); err != nil {
+		return err
+	}
+	if printConfig {
+		if err := setting.Print(os.Stdout); err != nil {
+			return err
+		}
+		os.Exit(0)
+	}
+	return nil
+}
+
+// setupOffline 初始化配置, 不解析 secret 引用, 不输出日志, 用于不启动服务的命令
+func setupOffline() error {
+	if err := setting.SetupOffline(configFile, profile); err != nil {
+		return err
+	}
+	if printConfig {
+		if err := setting.Print(os.Stdout); err != nil {
+			return err
+		}
+		os.Exit(0)
+	}
+	logging.SetupNop()
+	return nil
+}
+
+// setupLogging 初始化配置及日志
+func setupLogging() error {
+	if err := setupConfig(); err != nil {
+		return err
+	}
+	logging.Setup()
+	return nil
+}
+
+// setupDatabase 初始化配置, 日志及数据库
+func setupDatabase() error {
+	if err := setupLogging(); err != nil {
+		return err
+	}
+	if err := database.Setup(); err != nil {
+		logging.Logger.Error("database initialization failed", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// setupCache 初始化缓存, 需要先初始化日志
+func setupCache() error {
+	if err := cache.Setup(); err != nil {
+		logging.Logger.Error("cache initialization failed", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cobra"
+
+	"gin-example/routers"
+)
+
+// routesCmd 输出路由表, 只需要配置, 不需要密钥, 不连接数据库和 redis
+var routesCmd = &cobra.Command{
+	Use:   "routes",
+	Short: "Print the gin route table",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupOffline(); err != nil {
+			return err
+		}
+		gin.SetMode(gin.ReleaseMode)
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "METHOD\tPATH\tHANDLER")
+		for _, route := range routers.Routes() {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", route.Method, route.Path, route.Handler)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(routesCmd)
+}
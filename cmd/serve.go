@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"gin-example/models"
+	"gin-example/pkg/cache"
+	"gin-example/pkg/database"
+	"gin-example/pkg/health"
+	"gin-example/pkg/lifecycle"
+	"gin-example/pkg/logging"
+	"gin-example/pkg/server"
+	"gin-example/pkg/setting"
+	"gin-example/pkg/upload"
+	"gin-example/routers"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Start the web server",
+	Args:  cobra.NoArgs,
+	RunE:  runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(); err != nil {
+		return err
+	}
+	// 监听配置文件, 热加载 App, JWT, Log 配置
+	setting.Watch(func(err error) {
+		logging.Logger.Error("configuration reload rejected, keep the old one", zap.Error(err))
+	})
+	// 初始化缓存
+	if err := setupCache(); err != nil {
+		return err
+	}
+	// 初始化数据库
+	if err := database.Setup(); err != nil {
+		logging.Logger.Error("database initialization failed", zap.Error(err))
+		return err
+	}
+	// 数据库表结构变更
+	if err := models.Setup(); err != nil {
+		logging.Logger.Error("models initialization failed", zap.Error(err))
+		return err
+	}
+	// 就绪检查
+	health.Register("database", time.Second, database.Ping)
+	// 缓存和 session 都不使用 redis 时 (如本地 sqlite 模式) 不检查 redis
+	if cache.RedisEnabled() {
+		health.Register("redis", time.Second, cache.Ping)
+	}
+	health.Register("uploadSavePath", time.Second, func(ctx context.Context) error {
+		return upload.CheckWritable(setting.GetAppSetting().UploadSavePath)
+	})
+
+	gin.SetMode(setting.ServerSetting.RunMode)
+	router := routers.NewRouter()
+	srv, err := server.New(router)
+	if err != nil {
+		logging.Logger.Error("web server initialization failed", zap.Error(err))
+		return err
+	}
+	srv.Start()
+
+	// 关闭钩子按阶段执行: 先关闭服务器, 再停止后台任务 (session 清理), 然后释放数据库, redis, 最后刷新日志
+	lifecycle.OnShutdownStage("logger", lifecycle.StageLogger, func(ctx context.Context) error {
+		return logging.Sync()
+	})
+	lifecycle.OnShutdownStage("cache", lifecycle.StageResource, func(ctx context.Context) error {
+		return cache.Close()
+	})
+	lifecycle.OnShutdownStage("database", lifecycle.StageResource, func(ctx context.Context) error {
+		return database.Close()
+	})
+	lifecycle.OnShutdownStage("http server", lifecycle.StageServer, func(ctx context.Context) error {
+		// 先让就绪检查失败, 等待负载均衡摘除流量
+		health.SetShuttingDown()
+		select {
+		case <-time.After(setting.ServerSetting.ShutdownDrainDelay):
+		case <-ctx.Done():
+		}
+		// srv.Shutdown(ctx) 关闭服务器监听端口, 不再接受新的请求, 等待处理中的请求完成
+		return srv.Shutdown(ctx)
+	})
+
+	// kill -HUP 启动新进程接管监听, 当前进程处理完已接收的请求后退出
+	lifecycle.OnRestart(func() error {
+		pid, err := srv.Restart()
+		if err != nil {
+			return err
+		}
+		logging.Logger.Info("new process started", zap.Int("pid", pid))
+		return nil
+	})
+
+	// 等待 SIGINT, SIGTERM 信号以优雅地关闭服务器
+	lifecycle.Wait(setting.ServerSetting.ShutdownTimeout)
+	logging.Logger.Info("server shutdown completed !")
+	_ = logging.Sync()
+	return nil
+}
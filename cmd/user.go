@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"gin-example/models"
+	"gin-example/pkg/app"
+	"gin-example/service/users"
+)
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage users",
+}
+
+var userForm userssvc.User
+
+var userCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a user, a random password is generated if --password is empty",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if userForm.Role != models.RoleUser && userForm.Role != models.RoleAdmin {
+			return errors.Errorf("invalid role %s, use %s|%s", userForm.Role, models.RoleUser, models.RoleAdmin)
+		}
+		if err := setupDatabase(); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		password, generated, err := passwordOrRandom(userForm.Password)
+		if err != nil {
+			return err
+		}
+		user := userForm
+		if user.Password, err = app.Encrypt(password); err != nil {
+			return err
+		}
+		if err := userssvc.Default().Add(ctx, &user); err != nil {
+			if errors.Is(err, userssvc.ErrNameExists) {
+				return errors.Errorf("user %s already exists", user.Name)
+			}
+			return err
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "user %s created\n", user.Name)
+		if generated {
+			fmt.Fprintf(cmd.OutOrStdout(), "password: %s\n", password)
+		}
+		return nil
+	},
+}
+
+var userResetPasswordCmd = &cobra.Command{
+	Use:   "reset-password",
+	Short: "Reset the password of a user, a random password is generated if --password is empty",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupDatabase(); err != nil {
+			return err
+		}
+
+		password, generated, err := passwordOrRandom(userForm.Password)
+		if err != nil {
+			return err
+		}
+		user := userssvc.User{Name: userForm.Name, Password: password}
+		if err := userssvc.Default().ResetPassword(cmd.Context(), &user); err != nil {
+			return errors.Wrapf(err, "reset password of user %s", user.Name)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "password of user %s reset\n", user.Name)
+		if generated {
+			fmt.Fprintf(cmd.OutOrStdout(), "password: %s\n", password)
+		}
+		return nil
+	},
+}
+
+// passwordOrRandom password 为空时生成随机密码, 长度与注册接口一致在 6 到 20 之间
+func passwordOrRandom(password string) (string, bool, error) {
+	if password != "" {
+		if len(password) < 6 || len(password) > 20 {
+			return "", false, errors.New("password length must be between 6 and 20")
+		}
+		return password, false, nil
+	}
+
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", false, err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), true, nil
+}
+
+func init() {
+	createFlags := userCreateCmd.Flags()
+	createFlags.StringVar(&userForm.Name, "name", "", "user name")
+	createFlags.StringVar(&userForm.Password, "password", "", "password, 6 to 20 characters")
+	createFlags.StringVar(&userForm.Role, "role", models.RoleUser, "role, user|admin, admin can access /admin")
+	createFlags.StringVar(&userForm.Email, "email", "", "email")
+	createFlags.StringVar(&userForm.Gender, "gender", "", "gender")
+	_ = userCreateCmd.MarkFlagRequired("name")
+
+	resetFlags := userResetPasswordCmd.Flags()
+	resetFlags.StringVar(&userForm.Name, "name", "", "user name")
+	resetFlags.StringVar(&userForm.Password, "password", "", "new password, 6 to 20 characters")
+	_ = userResetPasswordCmd.MarkFlagRequired("name")
+
+	userCmd.AddCommand(userCreateCmd, userResetPasswordCmd)
+	rootCmd.AddCommand(userCmd)
+}
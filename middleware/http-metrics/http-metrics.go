@@ -0,0 +1,26 @@
+package httpmetrics
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"gin-example/pkg/metrics"
+)
+
+// 未匹配到路由的请求统一使用该标签, 避免任意路径造成指标基数膨胀
+const unmatchedRoute = "unmatched"
+
+// Metrics 按 gin 路由模板 (c.FullPath()), 请求方法, 状态码记录请求数和耗时
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		metrics.ObserveHTTPRequest(route, c.Request.Method, c.Writer.Status(), time.Since(start))
+	}
+}
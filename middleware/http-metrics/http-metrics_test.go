@@ -0,0 +1,132 @@
+package httpmetrics
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"gin-example/pkg/metrics"
+)
+
+func newRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Metrics())
+	r.GET("/tags/:id", func(c *gin.Context) {
+		if c.Param("id") == "0" {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	return r
+}
+
+func serve(r http.Handler, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+// observed 读取 GET 请求的计数器值或直方图的样本数, 指标注册在全局 Registry 中,
+// 测试比较前后的差值, 使 -count=N 重复执行时结果不变
+func observed(t *testing.T, name, route, status string) float64 {
+	t.Helper()
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["method"] == http.MethodGet && labels["route"] == route && labels["status"] == status {
+				if m.GetHistogram() != nil {
+					return float64(m.GetHistogram().GetSampleCount())
+				}
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+// 按路由模板而不是请求路径记录, 未匹配的路由统一为 unmatched
+func TestMetricsLabelsByRouteTemplate(t *testing.T) {
+	cases := []struct {
+		route, status string
+		want          float64
+	}{
+		{"/tags/:id", "200", 2},
+		{"/tags/:id", "500", 1},
+		{"unmatched", "404", 1},
+	}
+	metricNames := []string{"ginex_http_requests_total", "ginex_http_request_duration_seconds"}
+	before := make(map[string][]float64)
+	for _, name := range metricNames {
+		for _, c := range cases {
+			before[name] = append(before[name], observed(t, name, c.route, c.status))
+		}
+	}
+
+	r := newRouter()
+	serve(r, http.MethodGet, "/tags/1")
+	serve(r, http.MethodGet, "/tags/2")
+	serve(r, http.MethodGet, "/tags/0")
+	serve(r, http.MethodGet, "/no/such/path")
+
+	// 请求数和耗时的直方图使用相同的标签
+	for _, name := range metricNames {
+		for i, c := range cases {
+			if got := observed(t, name, c.route, c.status) - before[name][i]; got != c.want {
+				t.Errorf("%s{route=%q,status=%q} increased by %v, want %v", name, c.route, c.status, got, c.want)
+			}
+		}
+		if got := observed(t, name, "/tags/1", "200"); got != 0 {
+			t.Errorf("%s labeled by request path = %v, want 0", name, got)
+		}
+	}
+}
+
+// /metrics 使用独立的 Registry 输出文本格式, 不需要 prometheus 服务端
+func TestMetricsHandler(t *testing.T) {
+	r := newRouter()
+	sessionsBefore := testutil.ToFloat64(metrics.SessionsCreated)
+	uploadBefore := testutil.ToFloat64(metrics.UploadBytes)
+	metrics.SessionsCreated.Inc()
+	metrics.UploadBytes.Add(512)
+
+	w := serve(r, http.MethodGet, "/metrics")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	body, err := ioutil.ReadAll(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		fmt.Sprintf("ginex_session_created_total %v", sessionsBefore+1),
+		fmt.Sprintf("ginex_upload_bytes_total %v", uploadBefore+512),
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics output does not contain %q", want)
+		}
+	}
+
+	if got := testutil.ToFloat64(metrics.SessionsCreated) - sessionsBefore; got != 1 {
+		t.Errorf("sessions created increased by %v, want 1", got)
+	}
+}
@@ -0,0 +1,58 @@
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+
+	"gin-example/pkg/logging"
+)
+
+const (
+	HeaderXRequestID = "X-Request-ID"
+
+	// 客户端传入的请求 ID 超过该长度时重新生成
+	maxRequestIDLength = 128
+)
+
+// RequestID 使用请求头 X-Request-ID 作为请求 ID, 不存在或不合法时生成一个新的.
+// 请求 ID 保存在 c.Request.Context() 中, 并通过响应头 X-Request-ID 返回.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(HeaderXRequestID)
+		if !valid(requestID) {
+			requestID = generate()
+		}
+
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), requestID))
+		c.Header(HeaderXRequestID, requestID)
+
+		c.Next()
+	}
+}
+
+// Get 获取当前请求的请求 ID
+func Get(c *gin.Context) string {
+	return logging.RequestIDFromContext(c.Request.Context())
+}
+
+func valid(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func generate() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
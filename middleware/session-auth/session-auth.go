@@ -1,26 +1,92 @@
 package sessionauth
 
 import (
+	"context"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
 
 	"gin-example/models"
 	"gin-example/pkg/app"
+	"gin-example/pkg/database"
 	"gin-example/pkg/errcode"
 	"gin-example/pkg/gin-sessions"
+	"gin-example/pkg/lifecycle"
+	"gin-example/pkg/logging"
+	"gin-example/pkg/metrics"
+	"gin-example/pkg/setting"
+	"gin-example/service/users"
 )
 
-// 使用 Cookie 保存 session
+// 使用 Cookie 保存 session ID, session 数据按 Session.Store 保存
 func EnableCookieSession() gin.HandlerFunc {
-	store, err := ginsessions.NewRedisStore([]byte("secret"))
+	store, err := newSessionStore()
 	if err != nil {
 		panic(err)
 	}
-	store.SetRedisKeyPrefix("session:")
-	store.SetMaxAge(86400 * 2)
+	store.SetMaxAge(int(setting.SessionSetting.MaxAge / time.Second))
+	userSessionStore, _ = store.(ginsessions.UserSessionStoreInterface)
 
-	return ginsessions.Sessions("smp", store)
+	return ginsessions.Sessions(setting.SessionSetting.Name, store)
+}
+
+func newSessionStore() (ginsessions.GinStoreInterface, error) {
+	keys := setting.SessionSetting.Keys()
+	switch setting.SessionSetting.Store {
+	case "redis":
+		store, err := ginsessions.NewRedisStore(keys...)
+		if err != nil {
+			return nil, err
+		}
+		store.SetRedisKeyPrefix("session:")
+		store.SetUserKeyPrefix("session-user:")
+		return store, nil
+	case "memory":
+		store := ginsessions.NewMemoryStore(keys...)
+		if interval := setting.SessionSetting.CleanupInterval; interval > 0 {
+			stopCleanupOnShutdown(store.StartCleanup(interval))
+		}
+		return store, nil
+	case "database":
+		store := ginsessions.NewGormStore(database.GetGormDB(), keys...)
+		store.SetTable(setting.DatabaseSetting.TablePrefix + "session")
+		if interval := setting.SessionSetting.CleanupInterval; interval > 0 {
+			stopCleanupOnShutdown(store.StartCleanup(interval, func(err error) {
+				logging.Logger.Warn("cleanup expired sessions failed", zap.Error(err))
+			}))
+		}
+		return store, nil
+	case "filesystem":
+		// 目录不存在时保存 session 失败, 启动时创建
+		if path := setting.SessionSetting.FilesystemPath; path != "" {
+			if err := os.MkdirAll(path, 0700); err != nil {
+				return nil, errors.Wrapf(err, "create session directory %s", path)
+			}
+		}
+		store := ginsessions.NewFilesystemStore(setting.SessionSetting.FilesystemPath, keys...)
+		if interval := setting.SessionSetting.CleanupInterval; interval > 0 {
+			stopCleanupOnShutdown(store.StartCleanup(interval, func(err error) {
+				logging.Logger.Warn("cleanup expired session files failed", zap.Error(err))
+			}))
+		}
+		return store, nil
+	case "cookie":
+		return ginsessions.NewCookieStore(keys...), nil
+	default:
+		return nil, errors.Errorf("unknown Session.Store: %s, use redis|memory|database|filesystem|cookie", setting.SessionSetting.Store)
+	}
+}
+
+// stopCleanupOnShutdown 清理任务依赖数据库, 在 StageWorker 阶段停止, 早于数据库关闭
+func stopCleanupOnShutdown(stop func()) {
+	lifecycle.OnShutdownStage("session cleanup", lifecycle.StageWorker, func(ctx context.Context) error {
+		stop()
+		return nil
+	})
 }
 
 // session中间件
@@ -40,24 +106,57 @@ func AuthSessionMiddle() gin.HandlerFunc {
 
 		// 设置简单的变量
 		c.Set("userId", sessionValue.(uint))
+		touchUserSession(c, session, sessionValue.(uint))
 
 		c.Next()
 		return
 	}
 }
 
+// 管理员中间件, 需要在 AuthSessionMiddle 之后使用
+func AdminSessionMiddle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := userssvc.Default().Role(c.Request.Context(), c.MustGet("userId").(uint))
+		if err != nil || role != models.RoleAdmin {
+			appG := app.Gin{Context: c}
+			appG.Response(http.StatusForbidden,
+				errcode.PermissionDeniedError.WithDetails("需要管理员权限"),
+				struct{}{})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // 注册和登陆时都需要保存sessions信息
 func SaveAuthSession(c *gin.Context, id uint) error {
 	session := ginsessions.GetSession(c)
 	session.Set("userId", id)
-	return session.Save()
+	if err := session.Save(); err != nil {
+		return err
+	}
+	metrics.SessionsCreated.Inc()
+	touchUserSession(c, session, id)
+	return nil
 }
 
-// 退出时清除session
+// 退出时清除session, 同时从用户的 session 列表中删除
 func ClearAuthSession(c *gin.Context) error {
 	session := ginsessions.GetSession(c)
+	userID, _ := session.Get("userId").(uint)
 	session.Clear()
-	return session.Save()
+	if err := session.Save(); err != nil {
+		return err
+	}
+	metrics.SessionsDestroyed.Inc()
+	if userSessionStore != nil && userID > 0 {
+		if _, err := userSessionStore.RevokeUserSessions(c.Request.Context(), userID, session.ID()); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func HasSession(c *gin.Context) bool {
@@ -82,8 +181,9 @@ func GetUserSession(c *gin.Context) map[string]interface{} {
 	userName := ""
 	if hasSession {
 		userId := GetSessionUserId(c)
-		user, _ := models.UserDetail(userId)
-		userName = user.Name
+		if user, err := userssvc.Default().Detail(c.Request.Context(), userId); err == nil {
+			userName = user.Name
+		}
 	}
 	data := make(map[string]interface{})
 	data["hasSession"] = hasSession
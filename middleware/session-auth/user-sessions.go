@@ -0,0 +1,135 @@
+package sessionauth
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"gin-example/pkg/gin-sessions"
+	"gin-example/pkg/logging"
+	"gin-example/pkg/metrics"
+	"gin-example/pkg/sessions"
+)
+
+var (
+	// ErrUserSessionsNotSupported Session.Store 为 filesystem, cookie 时无法按用户查看, 删除 session
+	ErrUserSessionsNotSupported = errors.New("session store does not support user sessions")
+	// ErrSessionNotFound session 不存在或不属于当前用户
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// Session.Store 支持按用户管理 session 时不为 nil
+var userSessionStore ginsessions.UserSessionStoreInterface
+
+// UserSession 用户的一个登录设备
+type UserSession struct {
+	sessions.SessionInfo
+	// 是否为当前请求使用的 session
+	Current bool `json:"current"`
+}
+
+// touchUserSession 记录 session 的设备信息及最后访问时间, 失败时只记录日志, 下次请求时重新记录
+func touchUserSession(c *gin.Context, session ginsessions.GinSessionInterface, userID uint) {
+	if userSessionStore == nil || session.ID() == "" {
+		return
+	}
+	now := time.Now()
+	info := sessions.SessionInfo{
+		ID:         session.ID(),
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	if err := userSessionStore.TouchUserSession(c.Request.Context(), userID, info); err != nil {
+		logging.FromContext(c.Request.Context()).Warn("touch user session failed", zap.Uint("userId", userID), zap.Error(err))
+	}
+}
+
+// ListUserSessions 当前用户全部登录中的 session
+func ListUserSessions(c *gin.Context) ([]UserSession, error) {
+	if userSessionStore == nil {
+		return nil, ErrUserSessionsNotSupported
+	}
+	infos, err := userSessionStore.UserSessions(c.Request.Context(), GetSessionUserId(c))
+	if err != nil {
+		return nil, err
+	}
+
+	current := ginsessions.GetSession(c).ID()
+	userSessions := make([]UserSession, 0, len(infos))
+	for _, info := range infos {
+		userSessions = append(userSessions, UserSession{SessionInfo: info, Current: info.ID == current})
+	}
+	return userSessions, nil
+}
+
+// RevokeUserSession 删除当前用户的一个 session, 删除当前 session 时等同于退出
+func RevokeUserSession(c *gin.Context, id string) error {
+	if userSessionStore == nil {
+		return ErrUserSessionsNotSupported
+	}
+	if id == ginsessions.GetSession(c).ID() {
+		return ClearAuthSession(c)
+	}
+
+	n, err := revokeUserSessions(c.Request.Context(), GetSessionUserId(c), id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeOtherUserSessions 删除当前用户除当前 session 之外的全部 session, 返回删除的数量
+func RevokeOtherUserSessions(c *gin.Context) (int, error) {
+	if userSessionStore == nil {
+		return 0, ErrUserSessionsNotSupported
+	}
+	ctx := c.Request.Context()
+	userID := GetSessionUserId(c)
+	infos, err := userSessionStore.UserSessions(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	current := ginsessions.GetSession(c).ID()
+	ids := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if info.ID != current {
+			ids = append(ids, info.ID)
+		}
+	}
+	return revokeUserSessions(ctx, userID, ids...)
+}
+
+// RevokeAllUserSessions 删除用户的全部 session, 用于管理员强制下线, 返回删除的数量
+func RevokeAllUserSessions(ctx context.Context, userID uint) (int, error) {
+	if userSessionStore == nil {
+		return 0, ErrUserSessionsNotSupported
+	}
+	infos, err := userSessionStore.UserSessions(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]string, 0, len(infos))
+	for _, info := range infos {
+		ids = append(ids, info.ID)
+	}
+	return revokeUserSessions(ctx, userID, ids...)
+}
+
+func revokeUserSessions(ctx context.Context, userID uint, ids ...string) (int, error) {
+	n, err := userSessionStore.RevokeUserSessions(ctx, userID, ids...)
+	if n > 0 {
+		metrics.SessionsDestroyed.Add(float64(n))
+		logging.FromContext(ctx).Info("user sessions revoked", zap.Uint("userId", userID), zap.Int("count", n))
+	}
+	return n, err
+}
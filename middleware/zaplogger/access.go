@@ -0,0 +1,159 @@
+package zaplogger
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"gin-example/pkg/logging"
+)
+
+const defaultMaxBodySize = 4096
+
+// AccessConfig 访问日志配置
+type AccessConfig struct {
+	// 需要脱敏的参数名, 不区分大小写, 作用于 query, 表单及 json 请求/响应体
+	RedactFields []string
+	// 是否记录出错请求 (status >= 400) 的请求体和响应体
+	CaptureBody bool
+	// 请求体和响应体最多记录的字节数
+	MaxBodySize int
+	// 成功请求每秒记录前 SampleInitial 条, 之后每 SampleThereafter 条记录一条, 为 0 时不采样
+	SampleInitial    int
+	SampleThereafter int
+}
+
+// GinzapWithConfig returns a gin.HandlerFunc (middleware) that logs requests using uber-go/zap.
+//
+// Every request is logged once with route, status, size, latency, request id and user id.
+// All errors in c.Errors are attached to the line instead of replacing it.
+// Successful requests are sampled with SampleInitial/SampleThereafter,
+// failed requests are never sampled and may carry the redacted bodies:
+// status >= 500 is logged using zap.Error(), others using zap.Warn().
+func GinzapWithConfig(logger *zap.Logger, config AccessConfig) gin.HandlerFunc {
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = defaultMaxBodySize
+	}
+	redactor := newRedactor(config.RedactFields)
+
+	successLogger := logger
+	if config.SampleInitial > 0 && config.SampleThereafter > 0 {
+		successLogger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewSampler(core, time.Second, config.SampleInitial, config.SampleThereafter)
+		}))
+	}
+
+	return func(c *gin.Context) {
+		// some evil middleware modify this values
+		path := c.Request.URL.Path
+		query := c.Request.URL.RawQuery
+
+		var requestBody, responseBody *limitedBuffer
+		if config.CaptureBody {
+			if c.Request.Body != nil && capturable(c.ContentType()) {
+				requestBody = &limitedBuffer{limit: config.MaxBodySize}
+				c.Request.Body = teeReadCloser{Reader: io.TeeReader(c.Request.Body, requestBody), Closer: c.Request.Body}
+			}
+			responseBody = &limitedBuffer{limit: config.MaxBodySize}
+			c.Writer = &bodyWriter{ResponseWriter: c.Writer, body: responseBody}
+		}
+
+		start := time.Now()
+		c.Next()
+		requestTime := time.Now().Sub(start)
+
+		status := c.Writer.Status()
+		fields := []zap.Field{
+			zap.Int("status", status),
+			zap.String("method", c.Request.Method),
+			zap.String("route", c.FullPath()),
+			zap.String("path", path),
+			zap.String("query", redactor.query(query)),
+			zap.String("ip", c.ClientIP()),
+			zap.String("user_agent", c.Request.UserAgent()),
+			zap.Int("size", c.Writer.Size()),
+			zap.Duration("request_time", requestTime),
+			zap.String("request_id", logging.RequestIDFromContext(c.Request.Context())),
+		}
+		if userID, exists := c.Get("userId"); exists {
+			fields = append(fields, zap.Any("user_id", userID))
+		}
+
+		if len(c.Errors) == 0 && status < 400 {
+			successLogger.Info("http", fields...)
+			return
+		}
+
+		if len(c.Errors) > 0 {
+			// Append error field if this is an erroneous request.
+			fields = append(fields, zap.Strings("errors", c.Errors.Errors()))
+		}
+		if requestBody != nil {
+			fields = append(fields, zap.String("request_body", redactor.body(c.ContentType(), requestBody)))
+		}
+		if responseBody != nil {
+			fields = append(fields, zap.String("response_body", redactor.body(c.Writer.Header().Get("Content-Type"), responseBody)))
+		}
+		if status >= 500 {
+			logger.Error("http", fields...)
+			return
+		}
+		logger.Warn("http", fields...)
+	}
+}
+
+// capturable 只记录文本类请求体, 文件上传等不记录
+func capturable(contentType string) bool {
+	return contentType == gin.MIMEJSON || contentType == gin.MIMEPOSTForm
+}
+
+// limitedBuffer 最多保存 limit 字节, 超出部分丢弃
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Len() int {
+	return b.buf.Len()
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remain := b.limit - b.Len(); remain < len(p) {
+		b.truncated = true
+		if remain > 0 {
+			b.buf.Write(p[:remain])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyWriter 写响应的同时保存一份响应体
+type bodyWriter struct {
+	gin.ResponseWriter
+	body *limitedBuffer
+}
+
+func (w *bodyWriter) Write(b []byte) (int, error) {
+	_, _ = w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyWriter) WriteString(s string) (int, error) {
+	_, _ = w.body.Write([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,103 @@
+package zaplogger
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const redactedValue = "******"
+
+// 默认脱敏的参数名, 对应 LoginForm, AddUsersForm 的 password, AuthForm 的 appSecret 以及 token
+var defaultRedactFields = []string{"password", "appSecret", "token"}
+
+type redactor struct {
+	fields map[string]bool
+	// json 解析失败 (如被截断) 时按正则替换
+	jsonPattern *regexp.Regexp
+}
+
+func newRedactor(fields []string) *redactor {
+	if len(fields) == 0 {
+		fields = defaultRedactFields
+	}
+
+	r := &redactor{fields: make(map[string]bool, len(fields))}
+	quoted := make([]string, 0, len(fields))
+	for _, field := range fields {
+		r.fields[strings.ToLower(field)] = true
+		quoted = append(quoted, regexp.QuoteMeta(field))
+	}
+	r.jsonPattern = regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\s]+)`)
+	return r
+}
+
+func (r *redactor) sensitive(key string) bool {
+	return r.fields[strings.ToLower(key)]
+}
+
+// query 脱敏 url query 或 x-www-form-urlencoded 表单
+func (r *redactor) query(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return redactedValue
+	}
+	for key := range values {
+		if r.sensitive(key) {
+			values[key] = []string{redactedValue}
+		}
+	}
+	return strings.ReplaceAll(values.Encode(), url.QueryEscape(redactedValue), redactedValue)
+}
+
+func (r *redactor) body(contentType string, body *limitedBuffer) string {
+	var redacted string
+	switch {
+	case strings.HasPrefix(contentType, gin.MIMEPOSTForm):
+		redacted = r.query(body.String())
+	case strings.HasPrefix(contentType, gin.MIMEJSON):
+		redacted = r.json(body.String())
+	default:
+		redacted = body.String()
+	}
+	if body.truncated {
+		redacted += "...(truncated)"
+	}
+	return redacted
+}
+
+func (r *redactor) json(raw string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		return r.jsonPattern.ReplaceAllString(raw, `${1}"`+redactedValue+`"`)
+	}
+	b, err := json.Marshal(r.redactValue(v))
+	if err != nil {
+		return redactedValue
+	}
+	return string(b)
+}
+
+func (r *redactor) redactValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			if r.sensitive(key) {
+				value[key] = redactedValue
+				continue
+			}
+			value[key] = r.redactValue(item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = r.redactValue(item)
+		}
+	}
+	return v
+}
@@ -11,43 +11,18 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+
+	"gin-example/pkg/logging"
 )
 
 // Ginzap returns a gin.HandlerFunc (middleware) that logs requests using uber-go/zap.
 //
-// Requests with errors are logged using zap.Error().
+// Requests with errors or status >= 400 are logged using zap.Warn() or zap.Error().
 // Requests without errors are logged using zap.Info().
 //
-// It receives:
-//   1. A time package format string (e.g. time.RFC3339).
-//   2. A boolean stating whether to use UTC time zone or local.
+// It's GinzapWithConfig with the default AccessConfig.
 func Ginzap(logger *zap.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// some evil middleware modify this values
-		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
-
-		start := time.Now()
-		c.Next()
-		requestTime := time.Now().Sub(start)
-
-		if len(c.Errors) > 0 {
-			// Append error field if this is an erroneous request.
-			for _, e := range c.Errors.Errors() {
-				logger.Error(e)
-				return
-			}
-		}
-		logger.Info("http",
-			zap.Int("status", c.Writer.Status()),
-			zap.String("method", c.Request.Method),
-			zap.String("path", path),
-			zap.String("query", query),
-			zap.String("ip", c.ClientIP()),
-			zap.String("user_gent", c.Request.UserAgent()),
-			zap.Duration("request_time", requestTime),
-		)
-	}
+	return GinzapWithConfig(logger, AccessConfig{})
 }
 
 // RecoveryWithZap returns a gin.HandlerFunc (middleware)
@@ -72,6 +47,7 @@ func RecoveryWithZap(logger *zap.Logger, stack bool) gin.HandlerFunc {
 				}
 
 				httpRequest, _ := httputil.DumpRequest(c.Request, false)
+				logger := logger.With(logging.ContextFields(c.Request.Context())...)
 				if brokenPipe {
 					logger.Error("http",
 						zap.String("path", c.Request.URL.Path),
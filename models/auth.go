@@ -1,6 +1,8 @@
 package models
 
 import (
+	"context"
+
 	"gorm.io/gorm"
 
 	"gin-example/pkg/database"
@@ -11,14 +13,34 @@ type Auth struct {
 
 	AppKey    string `json:"app_key"`
 	AppSecret string `json:"app_secret"`
+	IsDel     int    `json:"is_del"`
+}
+
+// AuthRepository AppKey, AppSecret 的存储, ctx 取消时中断 SQL 执行
+type AuthRepository interface {
+	// Get 未找到时返回 ID 为 0 的 Auth
+	Get(ctx context.Context, appKey, appSecret string) (Auth, error)
+	Create(ctx context.Context, auth *Auth) error
+}
+
+type gormAuthRepository struct{}
+
+// NewAuthRepository 返回 GORM 实现的 AuthRepository
+func NewAuthRepository() AuthRepository {
+	return gormAuthRepository{}
 }
 
-func (a *Auth) Get() (Auth, error) {
+func (gormAuthRepository) Get(ctx context.Context, appKey, appSecret string) (Auth, error) {
 	var auth Auth
 
-	err := database.GetGormDB().Where("app_key = ? AND app_secret = ? AND is_del = ?", a.AppKey, a.AppSecret, 0).First(&auth).Error
+	err := database.DB(ctx).Where("app_key = ? AND app_secret = ? AND is_del = ?", appKey, appSecret, 0).First(&auth).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return auth, err
 	}
 	return auth, nil
 }
+
+// Create 新增 AppKey, AppSecret
+func (gormAuthRepository) Create(ctx context.Context, auth *Auth) error {
+	return database.DB(ctx).Create(auth).Error
+}
@@ -1,21 +1,32 @@
 package models
 
-import "gin-example/pkg/database"
+import (
+	"context"
+
+	// 注册全部 Migration
+	_ "gin-example/models/migrations"
+	"gin-example/pkg/database"
+	"gin-example/pkg/migrate"
+	"gin-example/pkg/setting"
+)
 
 func Setup() error {
-	if err := autoMigrateAll(); err != nil {
+	if !setting.DatabaseSetting.AutoMigrate {
+		return nil
+	}
+	migrator, err := NewMigrator(migrate.Options{})
+	if err != nil {
 		return err
 	}
-	return nil
+	return migrator.Up(context.Background(), 0)
 }
 
-func autoMigrateAll() error {
-	toMigrate := []interface{}{
-		&User{},
-		&Tag{},
-	}
-	if err := database.GetGormDB().AutoMigrate(toMigrate...); err != nil {
-		return err
+// NewMigrator 使用当前数据库连接及 Database.DBType 对应的 Migration 创建 Migrator
+func NewMigrator(options migrate.Options) (*migrate.Migrator, error) {
+	sqlDB, err := database.GetGormDB().DB()
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	options.TablePrefix = setting.DatabaseSetting.TablePrefix
+	return migrate.New(sqlDB, setting.DatabaseSetting.DBType, options)
 }
@@ -0,0 +1,155 @@
+package migrations
+
+import "gin-example/pkg/migrate"
+
+// 0001 与原 AutoMigrate 创建的表结构一致, 已有的表不受影响; auth 表补充 is_del, 已有的 auth 表通过 Columns 添加.
+// 每个数据库一份脚本, 表结构保持一致.
+func init() {
+	migrate.Register("mysql", migrate.Migration{
+		Version: 1,
+		Name:    "init",
+		Up: `
+CREATE TABLE IF NOT EXISTS {prefix}user (
+  id bigint unsigned NOT NULL AUTO_INCREMENT,
+  created_at datetime(3) NULL,
+  updated_at datetime(3) NULL,
+  deleted_at datetime(3) NULL,
+  name longtext,
+  password longtext,
+  role longtext,
+  email longtext,
+  gender longtext,
+  PRIMARY KEY (id),
+  INDEX idx_{prefix}user_deleted_at (deleted_at)
+);
+CREATE TABLE IF NOT EXISTS {prefix}tag (
+  id bigint unsigned NOT NULL AUTO_INCREMENT,
+  created_at datetime(3) NULL,
+  updated_at datetime(3) NULL,
+  deleted_at datetime(3) NULL,
+  name longtext,
+  created_by longtext,
+  modified_by longtext,
+  state bigint,
+  PRIMARY KEY (id),
+  INDEX idx_{prefix}tag_deleted_at (deleted_at)
+);
+CREATE TABLE IF NOT EXISTS {prefix}auth (
+  id bigint unsigned NOT NULL AUTO_INCREMENT,
+  created_at datetime(3) NULL,
+  updated_at datetime(3) NULL,
+  deleted_at datetime(3) NULL,
+  app_key longtext,
+  app_secret longtext,
+  is_del bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY (id),
+  INDEX idx_{prefix}auth_deleted_at (deleted_at)
+);
+`,
+		Down: `
+DROP TABLE IF EXISTS {prefix}auth;
+DROP TABLE IF EXISTS {prefix}tag;
+DROP TABLE IF EXISTS {prefix}user;
+`,
+		Columns: []migrate.Column{
+			{Table: "auth", Name: "is_del", Add: `ALTER TABLE {prefix}auth ADD COLUMN is_del bigint NOT NULL DEFAULT 0;`},
+		},
+	})
+
+	// user 是 postgres 的保留字, 表名需要加引号, sqlite 同样使用双引号
+	migrate.Register("postgres", migrate.Migration{
+		Version: 1,
+		Name:    "init",
+		Up: `
+CREATE TABLE IF NOT EXISTS "{prefix}user" (
+  id bigserial PRIMARY KEY,
+  created_at timestamptz,
+  updated_at timestamptz,
+  deleted_at timestamptz,
+  name text,
+  password text,
+  role text,
+  email text,
+  gender text
+);
+CREATE INDEX IF NOT EXISTS "idx_{prefix}user_deleted_at" ON "{prefix}user" (deleted_at);
+CREATE TABLE IF NOT EXISTS "{prefix}tag" (
+  id bigserial PRIMARY KEY,
+  created_at timestamptz,
+  updated_at timestamptz,
+  deleted_at timestamptz,
+  name text,
+  created_by text,
+  modified_by text,
+  state bigint
+);
+CREATE INDEX IF NOT EXISTS "idx_{prefix}tag_deleted_at" ON "{prefix}tag" (deleted_at);
+CREATE TABLE IF NOT EXISTS "{prefix}auth" (
+  id bigserial PRIMARY KEY,
+  created_at timestamptz,
+  updated_at timestamptz,
+  deleted_at timestamptz,
+  app_key text,
+  app_secret text,
+  is_del bigint NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS "idx_{prefix}auth_deleted_at" ON "{prefix}auth" (deleted_at);
+`,
+		Down: `
+DROP TABLE IF EXISTS "{prefix}auth";
+DROP TABLE IF EXISTS "{prefix}tag";
+DROP TABLE IF EXISTS "{prefix}user";
+`,
+		Columns: []migrate.Column{
+			{Table: "auth", Name: "is_del", Add: `ALTER TABLE "{prefix}auth" ADD COLUMN is_del bigint NOT NULL DEFAULT 0;`},
+		},
+	})
+
+	migrate.Register("sqlite", migrate.Migration{
+		Version: 1,
+		Name:    "init",
+		Up: `
+CREATE TABLE IF NOT EXISTS "{prefix}user" (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  created_at datetime,
+  updated_at datetime,
+  deleted_at datetime,
+  name text,
+  password text,
+  role text,
+  email text,
+  gender text
+);
+CREATE INDEX IF NOT EXISTS idx_{prefix}user_deleted_at ON "{prefix}user" (deleted_at);
+CREATE TABLE IF NOT EXISTS "{prefix}tag" (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  created_at datetime,
+  updated_at datetime,
+  deleted_at datetime,
+  name text,
+  created_by text,
+  modified_by text,
+  state integer
+);
+CREATE INDEX IF NOT EXISTS idx_{prefix}tag_deleted_at ON "{prefix}tag" (deleted_at);
+CREATE TABLE IF NOT EXISTS "{prefix}auth" (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  created_at datetime,
+  updated_at datetime,
+  deleted_at datetime,
+  app_key text,
+  app_secret text,
+  is_del integer NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_{prefix}auth_deleted_at ON "{prefix}auth" (deleted_at);
+`,
+		Down: `
+DROP TABLE IF EXISTS "{prefix}auth";
+DROP TABLE IF EXISTS "{prefix}tag";
+DROP TABLE IF EXISTS "{prefix}user";
+`,
+		Columns: []migrate.Column{
+			{Table: "auth", Name: "is_del", Add: `ALTER TABLE "{prefix}auth" ADD COLUMN is_del integer NOT NULL DEFAULT 0;`},
+		},
+	})
+}
@@ -0,0 +1,55 @@
+package migrations
+
+import "gin-example/pkg/migrate"
+
+// 0002 tag.name, user.name 唯一, 只约束未删除的记录, 软删除后可以重新使用同名.
+// 已存在重复数据时迁移失败, 需要先手动处理重复的记录.
+func init() {
+	// mysql 不支持部分索引, 使用未删除时取 name, 删除后为 NULL 的生成列建唯一索引
+	migrate.Register("mysql", migrate.Migration{
+		Version: 2,
+		Name:    "unique_name",
+		Up: `
+ALTER TABLE {prefix}tag MODIFY name varchar(100) NULL;
+ALTER TABLE {prefix}tag ADD COLUMN active_name varchar(100) GENERATED ALWAYS AS (IF(deleted_at IS NULL, name, NULL)) STORED;
+CREATE UNIQUE INDEX uk_{prefix}tag_active_name ON {prefix}tag (active_name);
+ALTER TABLE {prefix}user MODIFY name varchar(100) NULL;
+ALTER TABLE {prefix}user ADD COLUMN active_name varchar(100) GENERATED ALWAYS AS (IF(deleted_at IS NULL, name, NULL)) STORED;
+CREATE UNIQUE INDEX uk_{prefix}user_active_name ON {prefix}user (active_name);
+`,
+		Down: `
+DROP INDEX uk_{prefix}user_active_name ON {prefix}user;
+ALTER TABLE {prefix}user DROP COLUMN active_name;
+ALTER TABLE {prefix}user MODIFY name longtext;
+DROP INDEX uk_{prefix}tag_active_name ON {prefix}tag;
+ALTER TABLE {prefix}tag DROP COLUMN active_name;
+ALTER TABLE {prefix}tag MODIFY name longtext;
+`,
+	})
+
+	migrate.Register("postgres", migrate.Migration{
+		Version: 2,
+		Name:    "unique_name",
+		Up: `
+CREATE UNIQUE INDEX IF NOT EXISTS "uk_{prefix}tag_name" ON "{prefix}tag" (name) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS "uk_{prefix}user_name" ON "{prefix}user" (name) WHERE deleted_at IS NULL;
+`,
+		Down: `
+DROP INDEX IF EXISTS "uk_{prefix}user_name";
+DROP INDEX IF EXISTS "uk_{prefix}tag_name";
+`,
+	})
+
+	migrate.Register("sqlite", migrate.Migration{
+		Version: 2,
+		Name:    "unique_name",
+		Up: `
+CREATE UNIQUE INDEX IF NOT EXISTS uk_{prefix}tag_name ON "{prefix}tag" (name) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uk_{prefix}user_name ON "{prefix}user" (name) WHERE deleted_at IS NULL;
+`,
+		Down: `
+DROP INDEX IF EXISTS uk_{prefix}user_name;
+DROP INDEX IF EXISTS uk_{prefix}tag_name;
+`,
+	})
+}
@@ -0,0 +1,61 @@
+package migrations
+
+import "gin-example/pkg/migrate"
+
+// 0003 Session.Store 为 database 时保存 session 的表, 见 sessions.GormStore
+func init() {
+	migrate.Register("mysql", migrate.Migration{
+		Version: 3,
+		Name:    "session",
+		Up: `
+CREATE TABLE IF NOT EXISTS {prefix}session (
+  id varchar(64) NOT NULL,
+  data blob,
+  expires_at datetime(3) NOT NULL,
+  created_at datetime(3) NULL,
+  updated_at datetime(3) NULL,
+  PRIMARY KEY (id),
+  INDEX idx_{prefix}session_expires_at (expires_at)
+);
+`,
+		Down: `
+DROP TABLE IF EXISTS {prefix}session;
+`,
+	})
+
+	migrate.Register("postgres", migrate.Migration{
+		Version: 3,
+		Name:    "session",
+		Up: `
+CREATE TABLE IF NOT EXISTS "{prefix}session" (
+  id varchar(64) PRIMARY KEY,
+  data bytea,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz,
+  updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS "idx_{prefix}session_expires_at" ON "{prefix}session" (expires_at);
+`,
+		Down: `
+DROP TABLE IF EXISTS "{prefix}session";
+`,
+	})
+
+	migrate.Register("sqlite", migrate.Migration{
+		Version: 3,
+		Name:    "session",
+		Up: `
+CREATE TABLE IF NOT EXISTS "{prefix}session" (
+  id text PRIMARY KEY,
+  data blob,
+  expires_at datetime NOT NULL,
+  created_at datetime,
+  updated_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_{prefix}session_expires_at ON "{prefix}session" (expires_at);
+`,
+		Down: `
+DROP TABLE IF EXISTS "{prefix}session";
+`,
+	})
+}
@@ -0,0 +1,72 @@
+package migrations
+
+import "gin-example/pkg/migrate"
+
+// 0004 session 表记录所属的用户及设备信息, 用于查看登录设备及强制下线, 见 sessions.GormStore.TouchUserSession
+func init() {
+	migrate.Register("mysql", migrate.Migration{
+		Version: 4,
+		Name:    "session_user",
+		Up: `
+ALTER TABLE {prefix}session
+  ADD COLUMN user_id bigint unsigned NULL,
+  ADD COLUMN ip varchar(64) NULL,
+  ADD COLUMN user_agent text,
+  ADD COLUMN last_seen_at datetime(3) NULL,
+  ADD INDEX idx_{prefix}session_user_id (user_id);
+`,
+		Down: `
+ALTER TABLE {prefix}session
+  DROP INDEX idx_{prefix}session_user_id,
+  DROP COLUMN user_id,
+  DROP COLUMN ip,
+  DROP COLUMN user_agent,
+  DROP COLUMN last_seen_at;
+`,
+	})
+
+	migrate.Register("postgres", migrate.Migration{
+		Version: 4,
+		Name:    "session_user",
+		Up: `
+ALTER TABLE "{prefix}session"
+  ADD COLUMN IF NOT EXISTS user_id bigint,
+  ADD COLUMN IF NOT EXISTS ip varchar(64),
+  ADD COLUMN IF NOT EXISTS user_agent text,
+  ADD COLUMN IF NOT EXISTS last_seen_at timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_{prefix}session_user_id" ON "{prefix}session" (user_id);
+`,
+		Down: `
+DROP INDEX IF EXISTS "idx_{prefix}session_user_id";
+ALTER TABLE "{prefix}session"
+  DROP COLUMN IF EXISTS user_id,
+  DROP COLUMN IF EXISTS ip,
+  DROP COLUMN IF EXISTS user_agent,
+  DROP COLUMN IF EXISTS last_seen_at;
+`,
+	})
+
+	// sqlite 3.35 之前不支持 DROP COLUMN, 回滚时重建 session 表, 已登录的 session 失效
+	migrate.Register("sqlite", migrate.Migration{
+		Version: 4,
+		Name:    "session_user",
+		Up: `
+ALTER TABLE "{prefix}session" ADD COLUMN user_id integer;
+ALTER TABLE "{prefix}session" ADD COLUMN ip text;
+ALTER TABLE "{prefix}session" ADD COLUMN user_agent text;
+ALTER TABLE "{prefix}session" ADD COLUMN last_seen_at datetime;
+CREATE INDEX IF NOT EXISTS idx_{prefix}session_user_id ON "{prefix}session" (user_id);
+`,
+		Down: `
+DROP TABLE IF EXISTS "{prefix}session";
+CREATE TABLE IF NOT EXISTS "{prefix}session" (
+  id text PRIMARY KEY,
+  data blob,
+  expires_at datetime NOT NULL,
+  created_at datetime,
+  updated_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_{prefix}session_expires_at ON "{prefix}session" (expires_at);
+`,
+	})
+}
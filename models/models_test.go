@@ -0,0 +1,250 @@
+package models
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"gin-example/pkg/database"
+	"gin-example/pkg/logging"
+	"gin-example/pkg/migrate"
+	"gin-example/pkg/setting"
+)
+
+// TestMain 使用临时目录下的 sqlite 数据库文件, 执行全部 Migration 后运行测试
+func TestMain(m *testing.M) {
+	os.Exit(runWithSQLite(m))
+}
+
+func runWithSQLite(m *testing.M) int {
+	dir, err := ioutil.TempDir("", "gin-example-models")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	logging.Logger = zap.NewNop()
+	logging.GormLogger = zap.NewNop()
+	setting.DatabaseSetting = &setting.Database{
+		DBType:      "sqlite",
+		TablePrefix: "blog_",
+		SQLite: setting.SQLiteOptions{
+			Path:        filepath.Join(dir, "test.db"),
+			BusyTimeout: 5 * time.Second,
+		},
+		MaxOpenConns: 1,
+		AutoMigrate:  true,
+	}
+	if err := database.Setup(); err != nil {
+		panic(err)
+	}
+	defer database.Close()
+	if err := Setup(); err != nil {
+		panic(err)
+	}
+	return m.Run()
+}
+
+func TestTagRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTagRepository()
+
+	tag := &Tag{Name: "go", CreatedBy: "alice", State: 1}
+	if err := repo.Create(ctx, tag); err != nil {
+		t.Fatal(err)
+	}
+	if tag.ID == 0 {
+		t.Fatal("tag.ID is not set after Create")
+	}
+	if err := repo.Create(ctx, &Tag{Name: "rust", CreatedBy: "alice", State: 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未删除的记录 name 唯一
+	err := repo.Create(ctx, &Tag{Name: "go", CreatedBy: "bob", State: 1})
+	if !database.IsDuplicateKey(err) {
+		t.Fatalf("create duplicate tag: want duplicate key error, got %v", err)
+	}
+
+	if exist, err := repo.ExistByName(ctx, "go"); err != nil || !exist {
+		t.Fatalf("ExistByName(go) = %v, %v, want true", exist, err)
+	}
+	if exist, err := repo.ExistByID(ctx, int(tag.ID)); err != nil || !exist {
+		t.Fatalf("ExistByID(%d) = %v, %v, want true", tag.ID, exist, err)
+	}
+	if count, err := repo.Count(ctx, map[string]interface{}{"state": 1}); err != nil || count != 1 {
+		t.Fatalf("Count(state=1) = %d, %v, want 1", count, err)
+	}
+
+	tags, err := repo.List(ctx, 1, 1, map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 {
+		t.Fatalf("List page size 1 returned %d tags", len(tags))
+	}
+
+	if err := repo.Update(ctx, int(tag.ID), map[string]interface{}{"name": "golang", "modified_by": "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	tags, err = repo.List(ctx, 0, 10, map[string]interface{}{"name": "golang"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[0].ModifiedBy != "bob" {
+		t.Fatalf("List(name=golang) = %+v, want the updated tag", tags)
+	}
+
+	// 软删除后不再可见, 可以重新使用同名
+	if err := repo.Delete(ctx, int(tag.ID)); err != nil {
+		t.Fatal(err)
+	}
+	if exist, err := repo.ExistByID(ctx, int(tag.ID)); err != nil || exist {
+		t.Fatalf("ExistByID after Delete = %v, %v, want false", exist, err)
+	}
+	if err := repo.Create(ctx, &Tag{Name: "golang", CreatedBy: "bob", State: 1}); err != nil {
+		t.Fatalf("create tag with the name of a deleted tag: %v", err)
+	}
+}
+
+func TestUserRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository()
+
+	user := &User{Name: "alice", Password: "hashed", Role: RoleUser, Email: "alice@example.com"}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Create(ctx, &User{Name: "alice", Password: "hashed", Role: RoleUser}); !database.IsDuplicateKey(err) {
+		t.Fatalf("create duplicate user: want duplicate key error, got %v", err)
+	}
+
+	got, err := repo.GetByName(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != user.ID || got.Email != user.Email {
+		t.Fatalf("GetByName = %+v, want %+v", got, user)
+	}
+	if got, err = repo.GetByID(ctx, user.ID); err != nil || got.Name != "alice" {
+		t.Fatalf("GetByID = %+v, %v, want alice", got, err)
+	}
+	if _, err := repo.GetByID(ctx, user.ID+100); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("GetByID of missing user: want gorm.ErrRecordNotFound, got %v", err)
+	}
+
+	// List 不返回密码
+	users, err := repo.List(ctx, 0, 10, map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].Password != "" || users[0].Role != RoleUser {
+		t.Fatalf("List = %+v, want alice without password", users)
+	}
+
+	if err := repo.UpdatePasswordByName(ctx, "alice", "changed"); err != nil {
+		t.Fatal(err)
+	}
+	id, password, err := repo.GetPasswordByName(ctx, "alice")
+	if err != nil || id != user.ID || password != "changed" {
+		t.Fatalf("GetPasswordByName = %d, %q, %v, want %d, changed", id, password, err, user.ID)
+	}
+	if err := repo.UpdatePasswordByName(ctx, "nobody", "changed"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("UpdatePasswordByName of missing user: want gorm.ErrRecordNotFound, got %v", err)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTagRepository()
+	errRollback := errors.New("rollback")
+
+	// 外层事务回滚时全部修改都被撤销
+	err := database.Transaction(ctx, func(ctx context.Context) error {
+		if err := repo.Create(ctx, &Tag{Name: "tx-rollback", State: 1}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if err != errRollback {
+		t.Fatalf("Transaction = %v, want %v", err, errRollback)
+	}
+	if exist, err := repo.ExistByName(ctx, "tx-rollback"); err != nil || exist {
+		t.Fatalf("ExistByName after rollback = %v, %v, want false", exist, err)
+	}
+
+	// 内层保存点回滚只撤销内层的修改
+	err = database.Transaction(ctx, func(ctx context.Context) error {
+		if err := repo.Create(ctx, &Tag{Name: "tx-outer", State: 1}); err != nil {
+			return err
+		}
+		innerErr := database.Transaction(ctx, func(ctx context.Context) error {
+			if err := repo.Create(ctx, &Tag{Name: "tx-inner", State: 1}); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if innerErr != errRollback {
+			t.Errorf("inner Transaction = %v, want %v", innerErr, errRollback)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exist, err := repo.ExistByName(ctx, "tx-outer"); err != nil || !exist {
+		t.Fatalf("ExistByName(tx-outer) = %v, %v, want true", exist, err)
+	}
+	if exist, err := repo.ExistByName(ctx, "tx-inner"); err != nil || exist {
+		t.Fatalf("ExistByName(tx-inner) = %v, %v, want false", exist, err)
+	}
+}
+
+// Migration 之前由 AutoMigrate 创建的 auth 表没有 is_del, 执行 0001 时补充该列
+func TestMigrateExistingAuthTable(t *testing.T) {
+	ctx := context.Background()
+	db := database.GetGormDB()
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 使用单独的表前缀, 不影响其他测试使用的表
+	const prefix = "legacy_"
+	if err := db.Exec(`CREATE TABLE "legacy_auth" (id integer PRIMARY KEY AUTOINCREMENT, created_at datetime, updated_at datetime, deleted_at datetime, app_key text, app_secret text)`).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec(`INSERT INTO "legacy_auth" (app_key, app_secret) VALUES ('key', 'secret')`).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := migrate.New(sqlDB, "sqlite", migrate.Options{TablePrefix: prefix})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Up(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	var isDel []int
+	if err := db.Raw(`SELECT is_del FROM "legacy_auth"`).Scan(&isDel).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(isDel) != 1 || isDel[0] != 0 {
+		t.Fatalf("is_del = %v, want [0]", isDel)
+	}
+
+	// 表不存在时由 CREATE TABLE 创建完整的表, 不重复添加列
+	if err := migrator.Down(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Up(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+}
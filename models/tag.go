@@ -1,6 +1,8 @@
 package models
 
 import (
+	"context"
+
 	"gorm.io/gorm"
 
 	"gin-example/pkg/app"
@@ -16,20 +18,38 @@ type Tag struct {
 	State      int    `json:"state"`
 }
 
-func GetTagTotal(maps map[string]interface{}) (uint, error) {
+// TagRepository 标签的存储, ctx 取消时中断 SQL 执行
+type TagRepository interface {
+	Count(ctx context.Context, maps map[string]interface{}) (uint, error)
+	ExistByName(ctx context.Context, name string) (bool, error)
+	ExistByID(ctx context.Context, id int) (bool, error)
+	Create(ctx context.Context, tag *Tag) error
+	Update(ctx context.Context, id int, data map[string]interface{}) error
+	Delete(ctx context.Context, id int) error
+	List(ctx context.Context, pageNumber, pageSize int, maps interface{}) ([]Tag, error)
+}
+
+type gormTagRepository struct{}
+
+// NewTagRepository 返回 GORM 实现的 TagRepository
+func NewTagRepository() TagRepository {
+	return gormTagRepository{}
+}
+
+func (gormTagRepository) Count(ctx context.Context, maps map[string]interface{}) (uint, error) {
 	var count int64
 
-	if err := database.GetGormDB().Model(&Tag{}).Where(maps).Count(&count).Error; err != nil {
+	if err := database.DB(ctx).Model(&Tag{}).Where(maps).Count(&count).Error; err != nil {
 		return 0, err
 	}
 
 	return uint(count), nil
 }
 
-// ExistTagByName checks if there is a tag with the same name
-func ExistTagByName(name string) (bool, error) {
+// ExistByName checks if there is a tag with the same name
+func (gormTagRepository) ExistByName(ctx context.Context, name string) (bool, error) {
 	var tag Tag
-	err := database.GetGormDB().Select("id").Where("name = ?", name).First(&tag).Error
+	err := database.DB(ctx).Select("id").Where("name = ?", name).First(&tag).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return false, err
 	}
@@ -41,10 +61,10 @@ func ExistTagByName(name string) (bool, error) {
 	return false, nil
 }
 
-// ExistTagByID determines whether a Tag exists based on the ID
-func ExistTagByID(id int) (bool, error) {
+// ExistByID determines whether a Tag exists based on the ID
+func (gormTagRepository) ExistByID(ctx context.Context, id int) (bool, error) {
 	var tag Tag
-	err := database.GetGormDB().Select("id").Where("id = ?", id).First(&tag).Error
+	err := database.DB(ctx).Select("id").Where("id = ?", id).First(&tag).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return false, err
 	}
@@ -54,33 +74,19 @@ func ExistTagByID(id int) (bool, error) {
 	return false, nil
 }
 
-// AddTag Add a Tag
-func AddTag(name string, state int, createdBy string) error {
-	tag := Tag{
-		Name:      name,
-		State:     state,
-		CreatedBy: createdBy,
-	}
-	db := database.GetGormDB()
-	if err := db.Create(&tag).Error; err != nil {
-		return err
-	}
-
-	return nil
+// Create Add a Tag, tag.ID 为新增记录的 ID
+func (gormTagRepository) Create(ctx context.Context, tag *Tag) error {
+	return database.DB(ctx).Create(tag).Error
 }
 
-// EditTag modify a single tag
-func EditTag(id int, data interface{}) error {
-	if err := database.GetGormDB().Model(&Tag{}).Where("id = ?", id).Updates(data).Error; err != nil {
-		return err
-	}
-
-	return nil
+// Update modify a single tag
+func (gormTagRepository) Update(ctx context.Context, id int, data map[string]interface{}) error {
+	return database.DB(ctx).Model(&Tag{}).Where("id = ?", id).Updates(data).Error
 }
 
-// DeleteTag delete a tag
-func DeleteTag(id int) error {
-	if err := database.GetGormDB().Where("id = ?", id).Delete(&Tag{}).Error; err != nil {
+// Delete delete a tag
+func (gormTagRepository) Delete(ctx context.Context, id int) error {
+	if err := database.DB(ctx).Where("id = ?", id).Delete(&Tag{}).Error; err != nil {
 		return err
 	}
 
@@ -99,11 +105,11 @@ func DeleteTag(id int) error {
 	return nil
 }
 
-func GetTags(pageNumber, pageSize int, maps interface{}) ([]Tag, error) {
+func (gormTagRepository) List(ctx context.Context, pageNumber, pageSize int, maps interface{}) ([]Tag, error) {
 	var tags []Tag
 	pageOffset := app.GetPageOffset(pageNumber, pageSize)
 
-	if err := database.GetGormDB().Offset(pageOffset).Limit(pageSize).Where(maps).Find(&tags).Error; err != nil {
+	if err := database.DB(ctx).Offset(pageOffset).Limit(pageSize).Where(maps).Find(&tags).Error; err != nil {
 		return nil, err
 	}
 
@@ -1,12 +1,20 @@
 package models
 
 import (
+	"context"
+
 	"gorm.io/gorm"
 
 	"gin-example/pkg/app"
 	"gin-example/pkg/database"
 )
 
+// 管理员角色, 可以访问 /admin 接口, 只能通过 gin-example user create 或直接修改数据库授予
+const RoleAdmin = "admin"
+
+// 普通用户角色, 注册接口创建的用户都使用此角色
+const RoleUser = "user"
+
 type User struct {
 	gorm.Model
 
@@ -17,11 +25,33 @@ type User struct {
 	Gender   string `json:"gender"`
 }
 
-func GetUsers(pageNumber, pageSize int, maps interface{}) ([]User, error) {
+// UserRepository 用户的存储, ctx 取消时中断 SQL 执行
+type UserRepository interface {
+	// List 不返回密码
+	List(ctx context.Context, pageNumber, pageSize int, maps interface{}) ([]User, error)
+	Count(ctx context.Context, maps map[string]interface{}) (uint, error)
+	ExistByName(ctx context.Context, name string) (bool, error)
+	GetByName(ctx context.Context, name string) (*User, error)
+	GetByID(ctx context.Context, id uint) (*User, error)
+	// GetPasswordByName 返回用户 ID 及加密后的密码
+	GetPasswordByName(ctx context.Context, name string) (uint, string, error)
+	Create(ctx context.Context, user *User) error
+	// UpdatePasswordByName password 为加密后的密码, 用户不存在时返回 gorm.ErrRecordNotFound
+	UpdatePasswordByName(ctx context.Context, name, password string) error
+}
+
+type gormUserRepository struct{}
+
+// NewUserRepository 返回 GORM 实现的 UserRepository
+func NewUserRepository() UserRepository {
+	return gormUserRepository{}
+}
+
+func (gormUserRepository) List(ctx context.Context, pageNumber, pageSize int, maps interface{}) ([]User, error) {
 	var users []User
 	pageOffset := app.GetPageOffset(pageNumber, pageSize)
 
-	db := database.GetGormDB().Model(&User{}).Select("id, name, role, created_at, updated_at, deleted_at, email, gender")
+	db := database.DB(ctx).Model(&User{}).Select("id, name, role, created_at, updated_at, deleted_at, email, gender")
 	if err := db.Offset(pageOffset).Limit(pageSize).Where(maps).Scan(&users).Error; err != nil {
 		return nil, err
 	}
@@ -29,20 +59,20 @@ func GetUsers(pageNumber, pageSize int, maps interface{}) ([]User, error) {
 	return users, nil
 }
 
-func GetUsersTotal(maps map[string]interface{}) (uint, error) {
+func (gormUserRepository) Count(ctx context.Context, maps map[string]interface{}) (uint, error) {
 	var count int64
 
-	if err := database.GetGormDB().Model(&User{}).Where(maps).Count(&count).Error; err != nil {
+	if err := database.DB(ctx).Model(&User{}).Where(maps).Count(&count).Error; err != nil {
 		return 0, err
 	}
 
 	return uint(count), nil
 }
 
-// UserExistByName checks if there is a tag with the same name
-func UserExistByName(name string) (bool, error) {
+// ExistByName checks if there is a user with the same name
+func (gormUserRepository) ExistByName(ctx context.Context, name string) (bool, error) {
 	var user User
-	err := database.GetGormDB().Select("id").Where("name = ?", name).First(&user).Error
+	err := database.DB(ctx).Select("id").Where("name = ?", name).First(&user).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return false, err
 	}
@@ -54,43 +84,42 @@ func UserExistByName(name string) (bool, error) {
 	return false, nil
 }
 
-func UserDetailByName(name string) (*User, error) {
+func (gormUserRepository) GetByName(ctx context.Context, name string) (*User, error) {
 	var user User
-	if err := database.GetGormDB().Where("name = ?", name).First(&user).Error; err != nil {
+	if err := database.DB(ctx).Where("name = ?", name).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func UserDetail(id uint) (*User, error) {
+func (gormUserRepository) GetByID(ctx context.Context, id uint) (*User, error) {
 	var user User
-	if err := database.GetGormDB().Where("id = ?", id).First(&user).Error; err != nil {
+	if err := database.DB(ctx).Where("id = ?", id).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func GetUserPasswordByName(name string) (uint, string, error) {
+func (gormUserRepository) GetPasswordByName(ctx context.Context, name string) (uint, string, error) {
 	var user User
-	if err := database.GetGormDB().Select("id, password").Where("name = ?", name).First(&user).Error; err != nil {
+	if err := database.DB(ctx).Select("id, password").Where("name = ?", name).First(&user).Error; err != nil {
 		return 0, "", err
 	}
 	return user.ID, user.Password, nil
 }
 
-// AddUser
-func AddUser(name, password, role, email, gender string) error {
-	user := User{
-		Name:     name,
-		Password: password,
-		Role:     role,
-		Email:    email,
-		Gender:   gender,
+// Create user.ID 为新增记录的 ID
+func (gormUserRepository) Create(ctx context.Context, user *User) error {
+	return database.DB(ctx).Create(user).Error
+}
+
+func (gormUserRepository) UpdatePasswordByName(ctx context.Context, name, password string) error {
+	db := database.DB(ctx).Model(&User{}).Where("name = ?", name).Update("password", password)
+	if db.Error != nil {
+		return db.Error
 	}
-	db := database.GetGormDB()
-	if err := db.Create(&user).Error; err != nil {
-		return err
+	if db.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
 	}
-
 	return nil
 }
@@ -16,24 +16,25 @@ type Claims struct {
 }
 
 func GetJWTSecret() []byte {
-	return []byte(setting.JWTSetting.Secret)
+	return []byte(setting.GetJWTSetting().Secret)
 }
 
 func GenerateToken(appKey, appSecret string) (string, error) {
+	jwtSetting := setting.GetJWTSetting()
 	nowTime := time.Now()
-	expireTime := nowTime.Add(setting.JWTSetting.Expire)
+	expireTime := nowTime.Add(jwtSetting.Expire)
 
 	claims := Claims{
 		AppKey:    appKey,
 		AppSecret: appSecret,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: expireTime.Unix(),
-			Issuer:    setting.JWTSetting.Issuer,
+			Issuer:    jwtSetting.Issuer,
 		},
 	}
 	tokenClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	fmt.Println(tokenClaims)
-	return tokenClaims.SignedString(GetJWTSecret())
+	return tokenClaims.SignedString([]byte(jwtSetting.Secret))
 }
 
 func ParseToken(token string) (*Claims, error) {
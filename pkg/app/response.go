@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"gin-example/pkg/errcode"
+	"gin-example/pkg/logging"
 )
 
 type Gin struct {
@@ -13,6 +14,8 @@ type Gin struct {
 type Response struct {
 	*errcode.ErrorMessage
 	Data interface{} `json:"data"`
+	// 请求 ID, 与日志中的 request_id 及响应头 X-Request-ID 一致, 便于根据用户反馈查找日志
+	RequestID string `json:"requestId"`
 }
 
 // Response setting gin.JSON
@@ -20,6 +23,7 @@ func (g *Gin) Response(httpCode int, eMsg *errcode.ErrorMessage, data interface{
 	g.Context.JSON(httpCode, Response{
 		ErrorMessage: eMsg,
 		Data:         data,
+		RequestID:    logging.RequestIDFromContext(g.Context.Request.Context()),
 	})
 	return
 }
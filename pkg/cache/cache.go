@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+
+	"gin-example/pkg/logging"
+	"gin-example/pkg/metrics"
+)
+
+// ErrMiss 缓存中不存在或已过期
+var ErrMiss = errors.New("cache: miss")
+
+// Store 缓存后端, 只保存编码后的数据; ttl <= 0 时不过期
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Cache 通用缓存, 值以 json 编码保存, 同一个 key 并发加载时只执行一次 load
+type Cache struct {
+	store      Store
+	prefix     string
+	defaultTTL time.Duration
+	// 同一个 key 同时只执行一次 load, 其余调用等待并共享结果, 避免缓存失效时大量请求同时回源
+	group singleflight.Group
+}
+
+// New prefix 加在所有 key 之前, ttl <= 0 时使用 defaultTTL
+func New(store Store, prefix string, defaultTTL time.Duration) *Cache {
+	return &Cache{store: store, prefix: prefix, defaultTTL: defaultTTL}
+}
+
+// Get 读取 key 并解码到 dest, 不存在时返回 ErrMiss
+func (c *Cache) Get(ctx context.Context, key string, dest interface{}) error {
+	data, err := c.store.Get(ctx, c.prefix+key)
+	if err != nil {
+		if err == ErrMiss {
+			metrics.ObserveCache("miss")
+		} else {
+			metrics.ObserveCache("error")
+		}
+		return err
+	}
+	metrics.ObserveCache("hit")
+	return json.Unmarshal(data, dest)
+}
+
+func (c *Cache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return c.store.Set(ctx, c.prefix+key, data, c.ttl(ttl))
+}
+
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.prefix + key
+	}
+	return c.store.Delete(ctx, prefixed...)
+}
+
+// loadTimeout GetOrLoad 中 load 及写入缓存的超时时间
+const loadTimeout = 30 * time.Second
+
+// GetOrLoad 缓存未命中时调用 load 加载并写入缓存; 缓存不可用时直接使用 load 的结果, 不返回缓存的错误.
+// 并发请求同一个 key 时只有一个执行 load, 其余等待其结果; load 使用保留 ctx 中的值但不随 ctx 取消的 context,
+// 发起加载的请求取消时不影响其他等待的请求, 每个调用方只按自己的 ctx 停止等待.
+func (c *Cache) GetOrLoad(ctx context.Context, key string, dest interface{}, ttl time.Duration, load func(ctx context.Context) (interface{}, error)) error {
+	err := c.Get(ctx, key, dest)
+	if err == nil {
+		return nil
+	}
+	if err != ErrMiss {
+		logging.FromContext(ctx).Warn("cache get failed, load from source", zap.String("key", key), zap.Error(err))
+	}
+
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		loadCtx, cancel := context.WithTimeout(detach(ctx), loadTimeout)
+		defer cancel()
+
+		value, err := load(loadCtx)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.store.Set(loadCtx, c.prefix+key, data, c.ttl(ttl)); err != nil {
+			logging.FromContext(loadCtx).Warn("cache set failed", zap.String("key", key), zap.Error(err))
+		}
+		return data, nil
+	})
+	select {
+	case result := <-ch:
+		if result.Err != nil {
+			return result.Err
+		}
+		return json.Unmarshal(result.Val.([]byte), dest)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// detachedContext 保留 ctx 中的值 (request_id, 主库标记等), 不继承取消和超时
+type detachedContext struct {
+	context.Context
+}
+
+func detach(ctx context.Context) context.Context {
+	return detachedContext{ctx}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c *Cache) ttl(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return c.defaultTTL
+	}
+	return ttl
+}
+
+// nopStore Setup 之前使用, 不缓存任何数据, 命令行等未初始化缓存的场景直接读取数据源
+type nopStore struct{}
+
+func (nopStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, ErrMiss
+}
+
+func (nopStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return nil
+}
+
+func (nopStore) Delete(ctx context.Context, keys ...string) error {
+	return nil
+}
+
+var defaultCache = New(nopStore{}, "", 0)
+
+// Default 返回按 Cache 配置初始化的缓存, Setup 之前不缓存
+func Default() *Cache {
+	return defaultCache
+}
+
+// SetDefault 替换默认缓存, 用于测试时使用进程内缓存
+func SetDefault(c *Cache) {
+	defaultCache = c
+}
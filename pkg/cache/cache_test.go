@@ -0,0 +1,266 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gin-example/pkg/logging"
+)
+
+func init() {
+	logging.Logger = zap.NewNop()
+}
+
+// countingStore 记录 Get 的次数及最后一次 Set 的 ttl
+type countingStore struct {
+	Store
+	gets int32
+
+	mu  sync.Mutex
+	ttl map[string]time.Duration
+}
+
+func newCountingStore(store Store) *countingStore {
+	return &countingStore{Store: store, ttl: make(map[string]time.Duration)}
+}
+
+func (s *countingStore) Get(ctx context.Context, key string) ([]byte, error) {
+	atomic.AddInt32(&s.gets, 1)
+	return s.Store.Get(ctx, key)
+}
+
+func (s *countingStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	s.ttl[key] = ttl
+	s.mu.Unlock()
+	return s.Store.Set(ctx, key, value, ttl)
+}
+
+func (s *countingStore) lastTTL(key string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ttl[key]
+}
+
+// 同一个 key 并发未命中时只执行一次 load, 全部调用方得到相同的结果
+func TestGetOrLoadLoadsOnce(t *testing.T) {
+	for _, n := range []int{1, 10, 100} {
+		store := newCountingStore(NewMemoryStore(10))
+		c := New(store, "", time.Minute)
+
+		var loads int32
+		release := make(chan struct{})
+		load := func(ctx context.Context) (interface{}, error) {
+			atomic.AddInt32(&loads, 1)
+			<-release
+			return "value", nil
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, n)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var got string
+				if err := c.GetOrLoad(context.Background(), "key", &got, 0, load); err != nil {
+					errs <- err
+					return
+				}
+				if got != "value" {
+					errs <- fmt.Errorf("got %q, want value", got)
+				}
+			}()
+		}
+		// 全部调用方都已未命中后再结束 load
+		for atomic.LoadInt32(&store.gets) < int32(n) {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			t.Errorf("n=%d: %v", n, err)
+		}
+		if loads != 1 {
+			t.Errorf("n=%d: load called %d times, want 1", n, loads)
+		}
+	}
+}
+
+// 发起加载的调用方取消时只有它自己停止等待, load 继续执行, 结果供其他调用方使用并写入缓存
+func TestGetOrLoadCallerCancel(t *testing.T) {
+	c := New(NewMemoryStore(10), "", time.Minute)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	loadErr := make(chan error, 1)
+	load := func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-release
+		loadErr <- ctx.Err()
+		return "value", nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		var got string
+		first <- c.GetOrLoad(ctx, "key", &got, 0, load)
+	}()
+	<-started
+
+	second := make(chan error, 1)
+	var got string
+	go func() {
+		second <- c.GetOrLoad(context.Background(), "key", &got, 0, load)
+	}()
+
+	cancel()
+	if err := <-first; err != context.Canceled {
+		t.Fatalf("cancelled caller: want context.Canceled, got %v", err)
+	}
+	close(release)
+
+	if err := <-loadErr; err != nil {
+		t.Fatalf("load ctx is cancelled with the caller: %v", err)
+	}
+	if err := <-second; err != nil {
+		t.Fatal(err)
+	}
+	if got != "value" {
+		t.Fatalf("second caller got %q, want value", got)
+	}
+	var cached string
+	if err := c.Get(context.Background(), "key", &cached); err != nil || cached != "value" {
+		t.Fatalf("Get after load = %q, %v, want value", cached, err)
+	}
+}
+
+func TestMemoryStoreLRU(t *testing.T) {
+	cases := []struct {
+		name    string
+		ops     []string // set 或 get 的 key, 以 + 开头的为 set
+		evicted []string
+		kept    []string
+	}{
+		{name: "oldest evicted", ops: []string{"+a", "+b", "+c"}, evicted: []string{"a"}, kept: []string{"b", "c"}},
+		{name: "get refreshes", ops: []string{"+a", "+b", "a", "+c"}, evicted: []string{"b"}, kept: []string{"a", "c"}},
+		{name: "set refreshes", ops: []string{"+a", "+b", "+a", "+c"}, evicted: []string{"b"}, kept: []string{"a", "c"}},
+		{name: "within limit", ops: []string{"+a", "+b"}, kept: []string{"a", "b"}},
+	}
+
+	ctx := context.Background()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			store := NewMemoryStore(2)
+			for _, op := range c.ops {
+				if op[0] == '+' {
+					if err := store.Set(ctx, op[1:], []byte(op[1:]), 0); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				_, _ = store.Get(ctx, op)
+			}
+			for _, key := range c.evicted {
+				if _, err := store.Get(ctx, key); err != ErrMiss {
+					t.Errorf("Get(%s): want ErrMiss, got %v", key, err)
+				}
+			}
+			for _, key := range c.kept {
+				if data, err := store.Get(ctx, key); err != nil || string(data) != key {
+					t.Errorf("Get(%s) = %q, %v", key, data, err)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreTTL(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(10)
+	if err := store.Set(ctx, "short", []byte("v"), 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(ctx, "forever", []byte("v"), 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := store.Get(ctx, "short"); err != ErrMiss {
+		t.Errorf("Get expired key: want ErrMiss, got %v", err)
+	}
+	if _, err := store.Get(ctx, "forever"); err != nil {
+		t.Errorf("Get key without ttl: %v", err)
+	}
+}
+
+// 进程内缓存的过期时间为 min(ttl, localTTL), ttl 或 localTTL <= 0 表示不限制
+func TestTieredStoreLocalTTL(t *testing.T) {
+	cases := []struct {
+		name     string
+		ttl      time.Duration
+		localTTL time.Duration
+		want     time.Duration
+	}{
+		{name: "ttl longer", ttl: time.Minute, localTTL: 10 * time.Second, want: 10 * time.Second},
+		{name: "ttl shorter", ttl: 5 * time.Second, localTTL: 10 * time.Second, want: 5 * time.Second},
+		{name: "no ttl", ttl: 0, localTTL: 10 * time.Second, want: 10 * time.Second},
+		{name: "no localTTL", ttl: 5 * time.Second, localTTL: 0, want: 5 * time.Second},
+	}
+
+	ctx := context.Background()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			local, remote := newCountingStore(NewMemoryStore(10)), newCountingStore(NewMemoryStore(10))
+			store := NewTieredStore(local, remote, c.localTTL)
+			if err := store.Set(ctx, "key", []byte("v"), c.ttl); err != nil {
+				t.Fatal(err)
+			}
+			if got := remote.lastTTL("key"); got != c.ttl {
+				t.Errorf("remote ttl = %v, want %v", got, c.ttl)
+			}
+			if got := local.lastTTL("key"); got != c.want {
+				t.Errorf("local ttl = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+// 进程内未命中时从共享缓存读取并按 localTTL 写回进程内缓存
+func TestTieredStoreFillLocal(t *testing.T) {
+	ctx := context.Background()
+	local, remote := newCountingStore(NewMemoryStore(10)), newCountingStore(NewMemoryStore(10))
+	store := NewTieredStore(local, remote, 10*time.Second)
+	if err := remote.Set(ctx, "key", []byte("v"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if data, err := store.Get(ctx, "key"); err != nil || string(data) != "v" {
+			t.Fatalf("Get = %q, %v", data, err)
+		}
+	}
+	if remote.gets != 1 {
+		t.Fatalf("remote Get called %d times, want 1", remote.gets)
+	}
+	if got := local.lastTTL("key"); got != 10*time.Second {
+		t.Fatalf("local ttl = %v, want 10s", got)
+	}
+
+	if err := store.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, "key"); err != ErrMiss {
+		t.Fatalf("Get after Delete: want ErrMiss, got %v", err)
+	}
+}
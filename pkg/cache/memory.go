@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	key      string
+	value    []byte
+	expireAt time.Time
+}
+
+// memoryStore 进程内 LRU, 超过 maxEntries 时淘汰最久未使用的条目, 过期条目在读取时删除
+type memoryStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+// NewMemoryStore 进程内 LRU 缓存, 多个实例之间不共享
+func NewMemoryStore(maxEntries int) Store {
+	return &memoryStore{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+func (m *memoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.items[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	entry := elem.Value.(*memoryEntry)
+	if !entry.expireAt.IsZero() && time.Now().After(entry.expireAt) {
+		m.remove(elem)
+		return nil, ErrMiss
+	}
+	m.ll.MoveToFront(elem)
+	return entry.value, nil
+}
+
+func (m *memoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = time.Now().Add(ttl)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elem, ok := m.items[key]; ok {
+		entry := elem.Value.(*memoryEntry)
+		entry.value = value
+		entry.expireAt = expireAt
+		m.ll.MoveToFront(elem)
+		return nil
+	}
+	m.items[key] = m.ll.PushFront(&memoryEntry{key: key, value: value, expireAt: expireAt})
+	for m.maxEntries > 0 && m.ll.Len() > m.maxEntries {
+		m.remove(m.ll.Back())
+	}
+	return nil
+}
+
+func (m *memoryStore) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		if elem, ok := m.items[key]; ok {
+			m.remove(elem)
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) remove(elem *list.Element) {
+	m.ll.Remove(elem)
+	delete(m.items, elem.Value.(*memoryEntry).key)
+}
@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// redisStore 与 session 共用 redis 连接, 通过 key 前缀区分
+type redisStore struct {
+	client SessionCacheRedisClientInterface
+}
+
+// NewRedisStore 多个实例共享的 redis 缓存
+func NewRedisStore(client SessionCacheRedisClientInterface) Store {
+	return &redisStore{client: client}
+}
+
+func (r *redisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := r.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrMiss
+	}
+	return data, err
+}
+
+func (r *redisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl < 0 {
+		ttl = 0
+	}
+	return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (r *redisStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	// 集群模式下多个 key 可能不在同一个 slot, 逐个删除
+	for _, key := range keys {
+		if err := r.client.Del(ctx, key).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
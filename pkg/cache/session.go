@@ -2,6 +2,9 @@ package cache
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -9,44 +12,217 @@ import (
 	"go.uber.org/zap"
 
 	"gin-example/pkg/logging"
+	"gin-example/pkg/metrics"
 	"gin-example/pkg/setting"
 )
 
+// SessionCacheRedisClientInterface session 及通用缓存使用的 redis 命令, *redis.Client, *redis.ClusterClient 都实现此接口.
+// cluster 模式下 Scan 只扫描其中一个节点.
 type SessionCacheRedisClientInterface interface {
 	Ping(ctx context.Context) *redis.StatusCmd
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
 	Get(ctx context.Context, key string) *redis.StringCmd
 	Del(ctx context.Context, keys ...string) *redis.IntCmd
+	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
+	TTL(ctx context.Context, key string) *redis.DurationCmd
+	Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
+	Exists(ctx context.Context, keys ...string) *redis.IntCmd
+	HGet(ctx context.Context, key, field string) *redis.StringCmd
+	HGetAll(ctx context.Context, key string) *redis.StringStringMapCmd
+	HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
+	HDel(ctx context.Context, key string, fields ...string) *redis.IntCmd
+	PoolStats() *redis.PoolStats
+	Close() error
 }
 
 var sessionCache SessionCacheRedisClientInterface
 
+// RedisEnabled Cache.Type 为 redis|tiered 或 Session.Store 为 redis 时需要连接 redis
+func RedisEnabled() bool {
+	switch {
+	case setting.CacheSetting.Type == "redis", setting.CacheSetting.Type == "tiered":
+		return true
+	case setting.SessionSetting.Store == "redis":
+		return true
+	default:
+		return false
+	}
+}
+
 func Setup() error {
-	// 初始化 sessionCache
-	switch setting.SessionRedisSetting.Type {
+	// 初始化 sessionCache, 缓存和 session 都不使用 redis 时不创建连接
+	if RedisEnabled() {
+		if err := setupSessionCache(); err != nil {
+			return err
+		}
+	}
+
+	// 初始化通用缓存
+	switch setting.CacheSetting.Type {
+	case "memory":
+		defaultCache = New(NewMemoryStore(setting.CacheSetting.MemoryMaxEntries), setting.CacheSetting.KeyPrefix, setting.CacheSetting.DefaultTTL)
+	case "redis":
+		defaultCache = New(NewRedisStore(sessionCache), setting.CacheSetting.KeyPrefix, setting.CacheSetting.DefaultTTL)
+	case "tiered":
+		store := NewTieredStore(NewMemoryStore(setting.CacheSetting.MemoryMaxEntries), NewRedisStore(sessionCache), setting.CacheSetting.MemoryTTL)
+		defaultCache = New(store, setting.CacheSetting.KeyPrefix, setting.CacheSetting.DefaultTTL)
+	default:
+		return errors.Errorf("unknown Cache.Type: %s, use memory|redis|tiered", setting.CacheSetting.Type)
+	}
+	logging.Logger.Info("initialization cache ok.", zap.String("type", setting.CacheSetting.Type))
+
+	return nil
+}
+
+func setupSessionCache() error {
+	client, err := newRedisClient(setting.SessionRedisSetting)
+	if err != nil {
+		return err
+	}
+	sessionCache = client
+	if err := connect(setting.SessionRedisSetting); err != nil {
+		return err
+	}
+	if err := metrics.RegisterRedisPoolStats("session", sessionCache.PoolStats); err != nil {
+		return err
+	}
+	logging.Logger.Info("initialization sessionCache ok.", zap.String("mode", setting.SessionRedisSetting.Type))
+	return nil
+}
+
+func newRedisClient(redisSetting *setting.SessionRedis) (SessionCacheRedisClientInterface, error) {
+	var tlsConfig *tls.Config
+	if redisSetting.TLS.Enable {
+		var err error
+		if tlsConfig, err = newTLSConfig(redisSetting.TLS); err != nil {
+			return nil, err
+		}
+	}
+
+	switch redisSetting.Type {
 	case "singlePoint":
-		sessionCache = redis.NewClient(&redis.Options{
-			Addr:     setting.SessionRedisSetting.Address,
-			Password: setting.SessionRedisSetting.Password,
-		})
+		return redis.NewClient(&redis.Options{
+			Addr:         redisSetting.Address,
+			Username:     redisSetting.Username,
+			Password:     redisSetting.Password,
+			DB:           redisSetting.DB,
+			TLSConfig:    tlsConfig,
+			PoolSize:     redisSetting.PoolSize,
+			MinIdleConns: redisSetting.MinIdleConns,
+			DialTimeout:  redisSetting.DialTimeout,
+			ReadTimeout:  redisSetting.ReadTimeout,
+			WriteTimeout: redisSetting.WriteTimeout,
+			PoolTimeout:  redisSetting.PoolTimeout,
+			IdleTimeout:  redisSetting.IdleTimeout,
+		}), nil
 	case "cluster":
-		sessionCache = redis.NewClusterClient(&redis.ClusterOptions{
-			Addrs:          setting.SessionRedisSetting.Addresses,
-			Password:       setting.SessionRedisSetting.Password,
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:          redisSetting.Addresses,
+			Username:       redisSetting.Username,
+			Password:       redisSetting.Password,
 			RouteRandomly:  false,
 			RouteByLatency: false,
-		})
+			TLSConfig:      tlsConfig,
+			PoolSize:       redisSetting.PoolSize,
+			MinIdleConns:   redisSetting.MinIdleConns,
+			DialTimeout:    redisSetting.DialTimeout,
+			ReadTimeout:    redisSetting.ReadTimeout,
+			WriteTimeout:   redisSetting.WriteTimeout,
+			PoolTimeout:    redisSetting.PoolTimeout,
+			IdleTimeout:    redisSetting.IdleTimeout,
+		}), nil
+	case "sentinel":
+		// sentinel 与 redis 使用相同的 TLS 配置
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       redisSetting.MasterName,
+			SentinelAddrs:    redisSetting.SentinelAddresses,
+			SentinelPassword: redisSetting.SentinelPassword,
+			Username:         redisSetting.Username,
+			Password:         redisSetting.Password,
+			DB:               redisSetting.DB,
+			TLSConfig:        tlsConfig,
+			PoolSize:         redisSetting.PoolSize,
+			MinIdleConns:     redisSetting.MinIdleConns,
+			DialTimeout:      redisSetting.DialTimeout,
+			ReadTimeout:      redisSetting.ReadTimeout,
+			WriteTimeout:     redisSetting.WriteTimeout,
+			PoolTimeout:      redisSetting.PoolTimeout,
+			IdleTimeout:      redisSetting.IdleTimeout,
+		}), nil
 	default:
-		return errors.Errorf("unknown SessionRedisSetting.type: %s, use singlePoint|cluster", setting.SessionRedisSetting.Type)
+		return nil, errors.Errorf("unknown SessionRedisSetting.type: %s, use singlePoint|cluster|sentinel", redisSetting.Type)
 	}
-	if result, err := sessionCache.Ping(context.Background()).Result(); err != nil || result != "PONG" {
-		return err
+}
+
+func newTLSConfig(tlsSetting setting.RedisTLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         tlsSetting.ServerName,
+		InsecureSkipVerify: tlsSetting.InsecureSkipVerify,
 	}
-	logging.Logger.Info("initialization sessionCache ok.", zap.String("mode", setting.SessionRedisSetting.Type))
+	if tlsSetting.CAFile != "" {
+		pem, err := ioutil.ReadFile(tlsSetting.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "read redis CAFile")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificate found in redis CAFile: %s", tlsSetting.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if tlsSetting.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(tlsSetting.CertFile, tlsSetting.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "load redis client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
 
-	return nil
+// connect 检查 redis 是否可用, 失败时按 ConnectRetries 重试, redis 晚于服务启动时不会直接退出
+func connect(redisSetting *setting.SessionRedis) error {
+	backoff := redisSetting.ConnectRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := Ping(context.Background())
+		if err == nil || attempt >= redisSetting.ConnectRetries {
+			return err
+		}
+
+		logging.Logger.Warn("redis connect failed, retry",
+			zap.Int("attempt", attempt+1), zap.Int("retries", redisSetting.ConnectRetries), zap.Duration("backoff", backoff), zap.Error(err))
+		time.Sleep(backoff)
+		if backoff *= 2; redisSetting.ConnectRetryMaxBackoff > 0 && backoff > redisSetting.ConnectRetryMaxBackoff {
+			backoff = redisSetting.ConnectRetryMaxBackoff
+		}
+	}
 }
 
+// GetSessionCache RedisEnabled 为 false 时返回 nil
 func GetSessionCache() SessionCacheRedisClientInterface {
 	return sessionCache
 }
+
+// Ping 检查 sessionCache 是否可用, 用于就绪检查
+func Ping(ctx context.Context) error {
+	if sessionCache == nil {
+		return errors.New("redis is not enabled, Cache.Type and Session.Store do not use redis")
+	}
+	result, err := sessionCache.Ping(ctx).Result()
+	if err != nil {
+		return err
+	}
+	if result != "PONG" {
+		return errors.Errorf("unexpected ping result: %s", result)
+	}
+	return nil
+}
+
+// Close 关闭 sessionCache 连接池
+func Close() error {
+	if sessionCache == nil {
+		return nil
+	}
+	return sessionCache.Close()
+}
@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// tieredStore 进程内缓存在前, 共享缓存在后; 其他实例删除 key 后, 本实例最多在 localTTL 内读到旧数据
+type tieredStore struct {
+	local    Store
+	remote   Store
+	localTTL time.Duration
+}
+
+// NewTieredStore localTTL 为进程内缓存的最长时间, 不超过写入时的 ttl
+func NewTieredStore(local, remote Store, localTTL time.Duration) Store {
+	return &tieredStore{local: local, remote: remote, localTTL: localTTL}
+}
+
+func (t *tieredStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if data, err := t.local.Get(ctx, key); err == nil {
+		return data, nil
+	}
+	data, err := t.remote.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	_ = t.local.Set(ctx, key, data, t.localTTL)
+	return data, nil
+}
+
+func (t *tieredStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := t.remote.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	localTTL := t.localTTL
+	if ttl > 0 && (localTTL <= 0 || ttl < localTTL) {
+		localTTL = ttl
+	}
+	return t.local.Set(ctx, key, value, localTTL)
+}
+
+func (t *tieredStore) Delete(ctx context.Context, keys ...string) error {
+	_ = t.local.Delete(ctx, keys...)
+	return t.remote.Delete(ctx, keys...)
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"gin-example/pkg/setting"
+)
+
+// newDialector 按 DBType 生成 gorm 驱动
+func newDialector(databaseSetting *setting.Database) (gorm.Dialector, error) {
+	switch databaseSetting.DBType {
+	case "mysql":
+		return mysql.New(mysql.Config{DSN: mysqlDSN(databaseSetting)}), nil
+	case "postgres":
+		return postgres.New(postgres.Config{DSN: postgresDSN(databaseSetting)}), nil
+	case "sqlite":
+		dsn, err := sqliteDSN(databaseSetting.SQLite)
+		if err != nil {
+			return nil, err
+		}
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, errors.Errorf("unknown Database.DBType: %s, use mysql|postgres|sqlite", databaseSetting.DBType)
+	}
+}
+
+func mysqlDSN(databaseSetting *setting.Database) string {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=%s&parseTime=%t&loc=Local",
+		databaseSetting.UserName,
+		databaseSetting.Password,
+		databaseSetting.Host,
+		databaseSetting.DBName,
+		databaseSetting.Charset,
+		databaseSetting.ParseTime,
+	)
+	if databaseSetting.ConnectTimeout > 0 {
+		dsn += "&timeout=" + databaseSetting.ConnectTimeout.String()
+	}
+	return dsn
+}
+
+// postgresDSN 使用 URL 格式, 密码中的特殊字符无需转义
+func postgresDSN(databaseSetting *setting.Database) string {
+	query := url.Values{}
+	if databaseSetting.Postgres.SSLMode != "" {
+		query.Set("sslmode", databaseSetting.Postgres.SSLMode)
+	}
+	if databaseSetting.Postgres.TimeZone != "" {
+		query.Set("TimeZone", databaseSetting.Postgres.TimeZone)
+	}
+	// connect_timeout 单位为秒, 不足 1 秒按 1 秒
+	if timeout := databaseSetting.ConnectTimeout; timeout > 0 {
+		query.Set("connect_timeout", fmt.Sprint(int64((timeout+time.Second-1)/time.Second)))
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(databaseSetting.UserName, databaseSetting.Password),
+		Host:     databaseSetting.Host,
+		Path:     "/" + databaseSetting.DBName,
+		RawQuery: query.Encode(),
+	}
+	return dsn.String()
+}
+
+// sqliteDSN 数据库文件所在目录不存在时创建
+func sqliteDSN(options setting.SQLiteOptions) (string, error) {
+	if options.Path != ":memory:" {
+		if err := os.MkdirAll(filepath.Dir(options.Path), os.ModePerm); err != nil {
+			return "", errors.Wrap(err, "create sqlite directory")
+		}
+	}
+
+	query := url.Values{}
+	// 与 mysql, postgres 一致, 开启外键约束
+	query.Set("_foreign_keys", "1")
+	if options.BusyTimeout > 0 {
+		query.Set("_busy_timeout", fmt.Sprint(int64(options.BusyTimeout/time.Millisecond)))
+	}
+	if options.JournalMode != "" {
+		query.Set("_journal_mode", strings.ToUpper(options.JournalMode))
+	}
+	return "file:" + options.Path + "?" + query.Encode(), nil
+}
+
+// setPool 连接池配置, 主库与副本共用生命周期配置
+func setPool(sqlDB *sql.DB, databaseSetting *setting.Database, maxIdleConns, maxOpenConns int) {
+	sqlDB.SetMaxIdleConns(maxIdleConns)
+	sqlDB.SetMaxOpenConns(maxOpenConns)
+	sqlDB.SetConnMaxLifetime(databaseSetting.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(databaseSetting.ConnMaxIdleTime)
+}
+
+// statsName 数据库连接池指标的名称
+func statsName(databaseSetting *setting.Database) string {
+	if databaseSetting.DBType == "sqlite" {
+		return filepath.Base(databaseSetting.SQLite.Path)
+	}
+	return databaseSetting.DBName
+}
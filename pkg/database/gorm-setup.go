@@ -1,35 +1,29 @@
 package database
 
 import (
-	"fmt"
+	"context"
 	"time"
 
 	"go.uber.org/zap"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
 
 	"gin-example/pkg/gorm-zap"
 	"gin-example/pkg/logging"
+	"gin-example/pkg/metrics"
 	"gin-example/pkg/setting"
 )
 
-var gormDB *gorm.DB
+var (
+	gormDB       *gorm.DB
+	replicaPools *resolver
+)
 
 func Setup() error {
-	var err error
-
-	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=%s&parseTime=%t&loc=Local",
-		setting.DatabaseSetting.UserName,
-		setting.DatabaseSetting.Password,
-		setting.DatabaseSetting.Host,
-		setting.DatabaseSetting.DBName,
-		setting.DatabaseSetting.Charset,
-		setting.DatabaseSetting.ParseTime,
-	)
-	mysqlConfig := mysql.Config{
-		DSN: dsn,
+	dialector, err := newDialector(setting.DatabaseSetting)
+	if err != nil {
+		return err
 	}
 
 	gormConfig := gorm.Config{
@@ -40,7 +34,7 @@ func Setup() error {
 		},
 		Logger: gormLogger(),
 	}
-	gormDB, err = gorm.Open(mysql.New(mysqlConfig), &gormConfig)
+	gormDB, err = open(dialector, &gormConfig)
 	if err != nil {
 		return err
 	}
@@ -50,37 +44,101 @@ func Setup() error {
 		return err
 	}
 
-	sqlDB.SetMaxIdleConns(setting.DatabaseSetting.MaxIdleConns)
-	sqlDB.SetMaxOpenConns(setting.DatabaseSetting.MaxOpenConns)
+	setPool(sqlDB, setting.DatabaseSetting, setting.DatabaseSetting.MaxIdleConns, setting.DatabaseSetting.MaxOpenConns)
+	if err := gormDB.Use(&queryTimeout{timeout: setting.DatabaseSetting.QueryTimeout}); err != nil {
+		return err
+	}
+
+	if err := metrics.RegisterDBStats(statsName(setting.DatabaseSetting), sqlDB); err != nil {
+		return err
+	}
 
+	// 只读副本
+	if len(setting.DatabaseSetting.Replicas) > 0 {
+		if replicaPools, err = newResolver(setting.DatabaseSetting); err != nil {
+			return err
+		}
+		if err := gormDB.Use(replicaPools); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// open 连接数据库, 失败时按 ConnectRetries 重试, 数据库晚于服务启动时不会直接退出.
+// 每次失败都关闭该次创建的连接池, 重试期间不会累积连接池.
+func open(dialector gorm.Dialector, gormConfig *gorm.Config) (*gorm.DB, error) {
+	retries := setting.DatabaseSetting.ConnectRetries
+	backoff := setting.DatabaseSetting.ConnectRetryBackoff
+	maxBackoff := setting.DatabaseSetting.ConnectRetryMaxBackoff
+	for attempt := 0; ; attempt++ {
+		db, err := gorm.Open(dialector, gormConfig)
+		if err == nil {
+			return db, nil
+		}
+		// Ping 失败时 gorm.Open 已经创建了连接池
+		if sqlDB, dbErr := db.DB(); dbErr == nil {
+			_ = sqlDB.Close()
+		}
+		if attempt >= retries {
+			return nil, err
+		}
+
+		logging.Logger.Warn("database connect failed, retry",
+			zap.Int("attempt", attempt+1), zap.Int("retries", retries), zap.Duration("backoff", backoff), zap.Error(err))
+		time.Sleep(backoff)
+		if backoff *= 2; maxBackoff > 0 && backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// DB 返回绑定 ctx 的 gorm.DB, ctx 取消时中断 SQL 执行; 查询按 ctx 选择副本或主库, 见 WithPrimary.
+// ctx 来自 Transaction 时返回该事务.
+func DB(ctx context.Context) *gorm.DB {
+	if t := txFromContext(ctx); t != nil {
+		return t.db.WithContext(ctx)
+	}
+	return gormDB.WithContext(ctx)
+}
+
 func GetGormDB() *gorm.DB {
 	g := gormDB
 	return g
 }
 
-func gormLogLevel() gormzap.LogLevel {
-	switch setting.LoggerSetting.Level {
-	case zap.DebugLevel.String():
-		return gormzap.Info
-	case zap.ErrorLevel.String():
-		return gormzap.Error
-	case zap.WarnLevel.String():
-		return gormzap.Warn
-	case zap.InfoLevel.String():
-		return gormzap.Warn
-	default:
-		return gormzap.Silent
+// Close 关闭数据库及只读副本的连接池
+func Close() error {
+	if gormDB == nil {
+		return nil
+	}
+	if replicaPools != nil {
+		if err := replicaPools.close(); err != nil {
+			return err
+		}
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// Ping 检查数据库连接是否可用, 用于就绪检查
+func Ping(ctx context.Context) error {
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return err
 	}
+	return sqlDB.PingContext(ctx)
 }
 
 func gormLogger() logger.Interface {
 	if setting.DatabaseSetting.GormForceGormZapLog {
 		return gormzap.New(logging.GormLogger, gormzap.Config{
-			SlowThreshold: setting.DatabaseSetting.GormLogSlowThreshold * time.Millisecond,
-			LogLevel:      gormLogLevel(),
+			SlowThreshold: setting.DatabaseSetting.GormLogSlowThreshold.Duration(),
+			LogLevel:      gormzap.Info, // 由 gorm 日志的 zap 级别控制输出
+			ContextFields: logging.ContextFields,
 		})
 	}
 
@@ -89,8 +147,9 @@ func gormLogger() logger.Interface {
 		return logger.Default.LogMode(logger.Info)
 	default:
 		return gormzap.New(logging.GormLogger, gormzap.Config{
-			SlowThreshold: setting.DatabaseSetting.GormLogSlowThreshold * time.Millisecond,
-			LogLevel:      gormLogLevel(),
+			SlowThreshold: setting.DatabaseSetting.GormLogSlowThreshold.Duration(),
+			LogLevel:      gormzap.Info, // 由 gorm 日志的 zap 级别控制输出
+			ContextFields: logging.ContextFields,
 		})
 	}
 }
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"gin-example/pkg/logging"
+	"gin-example/pkg/metrics"
+	"gin-example/pkg/setting"
+)
+
+type primaryKey struct{}
+
+// WithPrimary 返回强制使用主库的 ctx, 用于写入后立即读取, 避免副本延迟读到旧数据
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func usePrimary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+type replica struct {
+	name string
+	db   *sql.DB
+	// 1 健康, 0 不健康, -1 未检查
+	healthy int32
+}
+
+// resolver gorm 插件, 事务之外的查询轮询发送到健康的副本, 没有健康的副本时使用主库
+type resolver struct {
+	replicas []*replica
+	next     uint32
+
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func (r *resolver) Name() string {
+	return "gin-example:resolver"
+}
+
+func (r *resolver) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Query().Before("gorm:query").Register("gin-example:resolver", r.resolve); err != nil {
+		return err
+	}
+	return db.Callback().Row().Before("gorm:row").Register("gin-example:resolver", r.resolve)
+}
+
+// resolve 只替换连接池为 *sql.DB 的语句, 事务中的连接池为 *sql.Tx, 保持使用主库
+func (r *resolver) resolve(db *gorm.DB) {
+	if _, ok := db.Statement.ConnPool.(*sql.DB); !ok {
+		return
+	}
+	if usePrimary(db.Statement.Context) {
+		return
+	}
+	if pool := r.pick(); pool != nil {
+		db.Statement.ConnPool = pool
+	}
+}
+
+func (r *resolver) pick() *sql.DB {
+	n := uint32(len(r.replicas))
+	start := atomic.AddUint32(&r.next, 1)
+	for i := uint32(0); i < n; i++ {
+		rep := r.replicas[(start+i)%n]
+		if atomic.LoadInt32(&rep.healthy) == 1 {
+			return rep.db
+		}
+	}
+	return nil
+}
+
+// watch 定时检查副本, 失败的副本移出轮询, 恢复后重新加入
+func (r *resolver) watch(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, rep := range r.replicas {
+				r.check(rep, interval)
+			}
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (r *resolver) check(rep *replica, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := rep.db.PingContext(ctx)
+	healthy := int32(1)
+	if err != nil {
+		healthy = 0
+	}
+	if atomic.SwapInt32(&rep.healthy, healthy) == healthy {
+		return
+	}
+	metrics.SetReplicaUp(rep.name, healthy == 1)
+	if err != nil {
+		logging.Logger.Warn("database replica unhealthy, removed from rotation", zap.String("replica", rep.name), zap.Error(err))
+		return
+	}
+	logging.Logger.Info("database replica healthy, in rotation", zap.String("replica", rep.name))
+}
+
+func (r *resolver) close() error {
+	r.stopOnce.Do(func() { close(r.done) })
+
+	var firstErr error
+	for _, rep := range r.replicas {
+		if err := rep.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// newResolver 按配置连接全部副本, 启动时不可用的副本先移出轮询, 由健康检查恢复
+func newResolver(databaseSetting *setting.Database) (*resolver, error) {
+	r := &resolver{done: make(chan struct{})}
+	for _, replicaSetting := range databaseSetting.Replicas {
+		replicaDatabaseSetting := *databaseSetting
+		replicaDatabaseSetting.Host = replicaSetting.Host
+		if replicaSetting.UserName != "" {
+			replicaDatabaseSetting.UserName = replicaSetting.UserName
+			replicaDatabaseSetting.Password = replicaSetting.Password
+		}
+
+		dialector, err := newDialector(&replicaDatabaseSetting)
+		if err != nil {
+			_ = r.close()
+			return nil, err
+		}
+		replicaDB, err := gorm.Open(dialector, &gorm.Config{DisableAutomaticPing: true, Logger: logger.Default.LogMode(logger.Silent)})
+		if err != nil {
+			_ = r.close()
+			return nil, err
+		}
+		sqlDB, err := replicaDB.DB()
+		if err != nil {
+			_ = r.close()
+			return nil, err
+		}
+		setPool(sqlDB, databaseSetting, replicaSetting.MaxIdleConns, replicaSetting.MaxOpenConns)
+
+		rep := &replica{name: replicaSetting.Host, db: sqlDB, healthy: -1}
+		r.replicas = append(r.replicas, rep)
+		if err := metrics.RegisterDBStats(statsName(databaseSetting)+"@"+rep.name, sqlDB); err != nil {
+			_ = r.close()
+			return nil, err
+		}
+		r.check(rep, 5*time.Second)
+	}
+
+	go r.watch(databaseSetting.ReplicaHealthCheckInterval)
+	return r, nil
+}
@@ -0,0 +1,14 @@
+//go:build !cgo
+// +build !cgo
+
+package database
+
+// 不启用 cgo 时 go-sqlite3 不可用, 只能使用 mysql, postgres
+
+func sqliteRetryable(err error) bool {
+	return false
+}
+
+func sqliteDuplicateKey(err error) bool {
+	return false
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type queryTimeoutKey struct{}
+
+// WithQueryTimeout 修改 ctx 中单条语句的超时时间, 覆盖 Database.QueryTimeout, timeout <= 0 时不限制.
+// ctx 已设置 deadline 时以 ctx 为准, 不再使用默认的超时时间.
+func WithQueryTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, queryTimeoutKey{}, timeout)
+}
+
+const queryCancelKey = "gin-example:query_timeout_cancel"
+
+// queryTimeout gorm 插件, 为没有 deadline 的语句设置默认的超时时间.
+// Row 返回的 *sql.Row 在回调结束后才读取, 不设置超时.
+type queryTimeout struct {
+	timeout time.Duration
+}
+
+func (q *queryTimeout) Name() string {
+	return "gin-example:query_timeout"
+}
+
+func (q *queryTimeout) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	for _, err := range []error{
+		callback.Create().Before("*").Register("gin-example:query_timeout", q.start),
+		callback.Create().After("*").Register("gin-example:query_timeout_cancel", q.cancel),
+		callback.Query().Before("*").Register("gin-example:query_timeout", q.start),
+		callback.Query().After("*").Register("gin-example:query_timeout_cancel", q.cancel),
+		callback.Update().Before("*").Register("gin-example:query_timeout", q.start),
+		callback.Update().After("*").Register("gin-example:query_timeout_cancel", q.cancel),
+		callback.Delete().Before("*").Register("gin-example:query_timeout", q.start),
+		callback.Delete().After("*").Register("gin-example:query_timeout_cancel", q.cancel),
+		callback.Raw().Before("*").Register("gin-example:query_timeout", q.start),
+		callback.Raw().After("*").Register("gin-example:query_timeout_cancel", q.cancel),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *queryTimeout) start(db *gorm.DB) {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return
+	}
+	timeout := q.timeout
+	if override, ok := ctx.Value(queryTimeoutKey{}).(time.Duration); ok {
+		timeout = override
+	}
+	if timeout <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	db.Statement.Context = ctx
+	db.InstanceSet(queryCancelKey, cancel)
+}
+
+// cancel gorm 读取结果时不检查 rows.Err(), 超时或取消后返回部分结果且没有错误, 这里补充 ctx 的错误
+func (q *queryTimeout) cancel(db *gorm.DB) {
+	if ctx := db.Statement.Context; ctx != nil && ctx.Err() != nil && db.Error == nil {
+		_ = db.AddError(ctx.Err())
+	}
+	if cancel, ok := db.InstanceGet(queryCancelKey); ok {
+		cancel.(context.CancelFunc)()
+	}
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgconn"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"gin-example/pkg/logging"
+	"gin-example/pkg/setting"
+)
+
+type txKey struct{}
+
+// tx 保存在 ctx 中的事务, depth 为嵌套层数, 用于生成保存点名称
+type tx struct {
+	db    *gorm.DB
+	depth int
+}
+
+func txFromContext(ctx context.Context) *tx {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(txKey{}).(*tx)
+	return t
+}
+
+// Transaction 在事务中执行 fn, fn 中通过 DB(ctx) 执行的语句都在同一事务中.
+// fn 返回错误或 panic 时回滚; 在事务中再次调用时使用保存点, 只回滚内层的修改.
+// 最外层的事务遇到死锁, 序列化失败时整体重试, fn 可能执行多次, 不要在 fn 中执行无法重复的操作.
+func Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if parent := txFromContext(ctx); parent != nil {
+		return savepoint(ctx, parent, fn)
+	}
+	return transaction(ctx, fn)
+}
+
+// TransactionRunner 事务的执行方式, 服务层保存为字段, 生产环境使用 Transaction, 测试时配合 fake 存储直接执行 fn
+type TransactionRunner func(ctx context.Context, fn func(ctx context.Context) error) error
+
+func transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	maxRetries := setting.DatabaseSetting.TxMaxRetries
+	backoff := setting.DatabaseSetting.TxRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := gormDB.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+			return fn(context.WithValue(ctx, txKey{}, &tx{db: db}))
+		})
+		if err == nil || !IsRetryable(err) || attempt >= maxRetries {
+			return err
+		}
+
+		logging.Logger.Warn("transaction conflict, retry",
+			zap.Int("attempt", attempt+1), zap.Duration("backoff", backoff), zap.Error(err))
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+func savepoint(ctx context.Context, parent *tx, fn func(ctx context.Context) error) (err error) {
+	child := &tx{db: parent.db, depth: parent.depth + 1}
+	name := fmt.Sprintf("sp%d", child.depth)
+	if err := parent.db.SavePoint(name).Error; err != nil {
+		return err
+	}
+
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			if rbErr := parent.db.RollbackTo(name).Error; rbErr != nil && err == nil {
+				err = rbErr
+			}
+		}
+	}()
+
+	err = fn(context.WithValue(ctx, txKey{}, child))
+	panicked = false
+	return err
+}
+
+// IsRetryable 死锁, 序列化失败等重试后可能成功的错误
+func IsRetryable(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		// 1213 死锁, 1205 等待锁超时
+		return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// 40001 序列化失败, 40P01 死锁
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+	return sqliteRetryable(err)
+}
+
+// IsDuplicateKey 违反唯一约束的错误
+func IsDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1062
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505"
+	}
+	return sqliteDuplicateKey(err)
+}
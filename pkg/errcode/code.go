@@ -22,6 +22,10 @@ var (
 	CookieSessionError = New("A0107", "CookieSession 错误")
 	CreateSessionError = New("A0108", "创建 Session 错误")
 	ClearSessionError  = New("A0109", "删除 Session 错误")
+	// 权限
+	PermissionDeniedError = New("A0110", "权限不足")
+	// 登录设备
+	SessionNotFoundError = New("A0111", "Session 不存在")
 
 	// B 组
 	// 服务端错误
@@ -42,6 +46,13 @@ var (
 	// 上传文件错误
 	UploadFileError = New("B0200", "上传文件失败")
 
+	// 日志级别错误
+	SetLogLevelError = New("B0300", "修改日志级别失败")
+
+	// 登录设备错误
+	GetSessionsError   = New("B0400", "获取登录设备失败")
+	RevokeSessionError = New("B0401", "下线登录设备失败")
+
 	// C 组
 	// 第三方调用错误
 	ThirdPartyCallError = New("C0001", "第三方调用错误")
@@ -31,7 +31,7 @@ func New(errorCode string, errorMessage string) *ErrorMessage {
 
 // 实现 Error 接口
 func (e *ErrorMessage) Error() string {
-	return fmt.Sprintf("error code: %d, error message :%s\n", e.Code, e.Message)
+	return fmt.Sprintf("error code: %s, error message :%s\n", e.Code, e.Message)
 }
 
 // 添加错误详细描述信息
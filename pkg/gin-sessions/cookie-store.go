@@ -1,19 +1,24 @@
 package ginsessions
 
-//import (
-//	"gin-example/pkg/gin-sessions/sessions"
-//)
-//
-//type cookieStore struct {
-//	*sessions.CookieStore
-//}
-//
-//func NewCookieStore(keyPairs ...[]byte) GinStoreInterface {
-//	return &cookieStore{
-//		CookieStore: sessions.NewCookieStore(keyPairs...),
-//	}
-//}
-//
-//func (c *cookieStore) CovertOptions(options Options) {
-//	c.CookieStore.Options = options.ToOptions()
-//}
+import (
+	"gin-example/pkg/sessions"
+)
+
+// cookieStore session 数据全部保存在 cookie 中, 服务端无法使 session 提前失效
+type cookieStore struct {
+	*sessions.CookieStore
+}
+
+func NewCookieStore(keyPairs ...[]byte) GinStoreInterface {
+	return &cookieStore{
+		CookieStore: sessions.NewCookieStore(keyPairs...),
+	}
+}
+
+func (c *cookieStore) SetMaxAge(maxAge int) {
+	c.CookieStore.MaxAge(maxAge)
+}
+
+func (c *cookieStore) CovertOptions(options Options) {
+	c.CookieStore.Options = options.ToOptions()
+}
@@ -0,0 +1,34 @@
+package ginsessions
+
+import (
+	"time"
+
+	"gin-example/pkg/sessions"
+)
+
+type filesystemStore struct {
+	*sessions.FilesystemStore
+}
+
+// FilesystemStoreInterface 文件存储, 需要定时清理过期的 session 文件
+type FilesystemStoreInterface interface {
+	GinStoreInterface
+
+	// 每隔 interval 删除超过 MaxAge 未保存的 session 文件, 调用返回的函数停止
+	StartCleanup(interval time.Duration, onError func(err error)) (stop func())
+}
+
+// NewFilesystemStore path 为空时使用系统临时目录
+func NewFilesystemStore(path string, keyPairs ...[]byte) FilesystemStoreInterface {
+	return &filesystemStore{
+		FilesystemStore: sessions.NewFilesystemStore(path, keyPairs...),
+	}
+}
+
+func (c *filesystemStore) SetMaxAge(maxAge int) {
+	c.FilesystemStore.MaxAge(maxAge)
+}
+
+func (c *filesystemStore) CovertOptions(options Options) {
+	c.FilesystemStore.Options = options.ToOptions()
+}
@@ -0,0 +1,31 @@
+package ginsessions
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"gin-example/pkg/sessions"
+)
+
+type gormStore struct {
+	*sessions.GormStore
+}
+
+// GormStoreInterface 数据库存储, 需要定时清理过期的 session
+type GormStoreInterface interface {
+	GinStoreInterface
+
+	// set session table name, including table prefix
+	SetTable(table string)
+	// 每隔 interval 删除过期的 session, 调用返回的函数停止
+	StartCleanup(interval time.Duration, onError func(err error)) (stop func())
+}
+
+func NewGormStore(db *gorm.DB, keyPairs ...[]byte) GormStoreInterface {
+	return &gormStore{GormStore: sessions.NewGormStore(db, keyPairs...)}
+}
+
+func (c *gormStore) CovertOptions(options Options) {
+	c.GormStore.Options = options.ToOptions()
+}
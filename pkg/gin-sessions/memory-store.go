@@ -0,0 +1,27 @@
+package ginsessions
+
+import (
+	"time"
+
+	"gin-example/pkg/sessions"
+)
+
+type memoryStore struct {
+	*sessions.MemoryStore
+}
+
+// MemoryStoreInterface 进程内存储, 需要定时清理过期的 session
+type MemoryStoreInterface interface {
+	GinStoreInterface
+
+	// 每隔 interval 删除过期的 session, 调用返回的函数停止
+	StartCleanup(interval time.Duration) (stop func())
+}
+
+func NewMemoryStore(keyPairs ...[]byte) MemoryStoreInterface {
+	return &memoryStore{MemoryStore: sessions.NewMemoryStore(keyPairs...)}
+}
+
+func (c *memoryStore) CovertOptions(options Options) {
+	c.MemoryStore.Options = options.ToOptions()
+}
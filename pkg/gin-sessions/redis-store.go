@@ -9,22 +9,21 @@ type redisStore struct {
 	*sessions.RedisStore
 }
 
-// GinStoreInterface
-type GinStoreInterface interface {
-	// sessions.Store
-	sessions.Store
+// RedisStoreInterface redis 存储特有的配置
+type RedisStoreInterface interface {
+	GinStoreInterface
 
-	// set http.cookie options default max age
-	SetMaxAge(maxAge int)
 	// set http.cookie options max length
 	SetMaxLength(maxLength int)
 	// set store redis key prefix
 	SetRedisKeyPrefix(prefix string)
+	// set user session index key prefix
+	SetUserKeyPrefix(prefix string)
 	// set SetSerializer method
 	SetSerializer(i sessions.SessionSerializerInterface)
 }
 
-func NewRedisStore(keyPairs ...[]byte) (GinStoreInterface, error) {
+func NewRedisStore(keyPairs ...[]byte) (RedisStoreInterface, error) {
 	rs, err := sessions.NewRedisStore(cache.GetSessionCache(), keyPairs...)
 	if err != nil {
 		return nil, err
@@ -36,6 +36,8 @@ type GinSessionInterface interface {
 	Options(Options)
 	// Save saves all sessions used during the current request.
 	Save() error
+	// ID returns the session ID, empty before the new session is saved.
+	ID() string
 }
 
 type ginSession struct {
@@ -115,6 +117,10 @@ func (s *ginSession) Options(options Options) {
 	s.Session().Options = options.ToOptions()
 }
 
+func (s *ginSession) ID() string {
+	return s.Session().ID
+}
+
 func (s *ginSession) NeedWritten() bool {
 	return s.needWritten
 }
@@ -0,0 +1,20 @@
+package ginsessions
+
+import (
+	"gin-example/pkg/sessions"
+)
+
+// GinStoreInterface 实现 SetMaxAge 的 sessions.Store 都可以作为 gin session 的存储
+type GinStoreInterface interface {
+	// sessions.Store
+	sessions.Store
+
+	// set session default max age, in seconds
+	SetMaxAge(maxAge int)
+}
+
+// UserSessionStoreInterface 可以按用户查看, 删除 session 的存储, redis, memory, database 支持
+type UserSessionStoreInterface interface {
+	GinStoreInterface
+	sessions.UserSessionStore
+}
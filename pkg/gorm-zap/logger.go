@@ -22,6 +22,8 @@ const (
 type Config struct {
 	SlowThreshold time.Duration
 	LogLevel      LogLevel
+	// ContextFields 从 gorm WithContext 传入的 ctx 中提取需要记录的字段, 如请求 ID
+	ContextFields func(ctx context.Context) []zap.Field
 }
 
 func New(zap *zap.Logger, config Config) logger.Interface {
@@ -43,12 +45,19 @@ func (g *Logger) LogMode(level logger.LogLevel) logger.Interface {
 	return &newLogger
 }
 
+func (g Logger) contextFields(ctx context.Context) []zap.Field {
+	if g.ContextFields == nil {
+		return nil
+	}
+	return g.ContextFields(ctx)
+}
+
 // Info print info
 func (g Logger) Info(ctx context.Context, msg string, data ...interface{}) {
 	if g.LogLevel < Info {
 		return
 	}
-	g.Logger.Sugar().Debugf(msg, append([]interface{}{utils.FileWithLineNum()}, data...))
+	g.Logger.With(g.contextFields(ctx)...).Sugar().Debugf(msg, append([]interface{}{utils.FileWithLineNum()}, data...))
 }
 
 // Warn print warn messages
@@ -56,7 +65,7 @@ func (g Logger) Warn(ctx context.Context, msg string, data ...interface{}) {
 	if g.LogLevel < Warn {
 		return
 	}
-	g.Logger.Sugar().Warnf(msg, append([]interface{}{utils.FileWithLineNum()}, data...))
+	g.Logger.With(g.contextFields(ctx)...).Sugar().Warnf(msg, append([]interface{}{utils.FileWithLineNum()}, data...))
 }
 
 // Error print error messages
@@ -64,18 +73,19 @@ func (g Logger) Error(ctx context.Context, msg string, data ...interface{}) {
 	if g.LogLevel < Error {
 		return
 	}
-	g.Logger.Sugar().Errorf(msg, append([]interface{}{utils.FileWithLineNum()}, data...))
+	g.Logger.With(g.contextFields(ctx)...).Sugar().Errorf(msg, append([]interface{}{utils.FileWithLineNum()}, data...))
 }
 
-// Trace print sql message
+// Trace print sql message, zap logger 的级别同样生效, 运行期间调整 zap 级别即可控制输出
 func (g Logger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
 	if g.LogLevel >= Silent {
 		elapsed := time.Since(begin)
+		log := g.Logger.With(g.contextFields(ctx)...)
 		switch {
 		// gorm error log
-		case err != nil && logger.LogLevel(g.LogLevel) >= logger.Error:
+		case err != nil && logger.LogLevel(g.LogLevel) >= logger.Error && g.Core().Enabled(zap.ErrorLevel):
 			sql, rows := fc()
-			g.Logger.Error("gorm",
+			log.Error("gorm",
 				zap.String("caller", utils.FileWithLineNum()),
 				zap.Float64("elapsed_ms", float64(elapsed.Nanoseconds())/1e6),
 				zap.String("sql", sql),
@@ -83,18 +93,18 @@ func (g Logger) Trace(ctx context.Context, begin time.Time, fc func() (string, i
 				zap.Error(err),
 			)
 		// gorm warning log
-		case elapsed > g.SlowThreshold && g.SlowThreshold != 0 && logger.LogLevel(g.LogLevel) >= logger.Warn:
+		case elapsed > g.SlowThreshold && g.SlowThreshold != 0 && logger.LogLevel(g.LogLevel) >= logger.Warn && g.Core().Enabled(zap.WarnLevel):
 			sql, rows := fc()
-			g.Logger.Warn("gorm",
+			log.Warn("gorm",
 				zap.String("caller", utils.FileWithLineNum()),
 				zap.Float64("elapsed_ms", float64(elapsed.Nanoseconds())/1e6),
 				zap.String("sql", sql),
 				zap.Int64("affect_rows", rows),
 			)
 		// gorm info log, it can be considered all log or debug log and so on ...
-		case logger.LogLevel(g.LogLevel) >= logger.Info:
+		case logger.LogLevel(g.LogLevel) >= logger.Info && g.Core().Enabled(zap.DebugLevel):
 			sql, rows := fc()
-			g.Logger.Debug("gorm",
+			log.Debug("gorm",
 				zap.String("caller", utils.FileWithLineNum()),
 				zap.Float64("elapsed_ms", float64(elapsed.Nanoseconds())/1e6),
 				zap.String("sql", sql),
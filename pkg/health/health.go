@@ -0,0 +1,129 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// 未指定超时时间的检查使用的默认超时
+const DefaultTimeout = 2 * time.Second
+
+// CheckFunc 检查一个依赖是否可用, 需要响应 ctx 的超时
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      CheckFunc
+}
+
+// CheckResult 单个依赖的检查结果
+type CheckResult struct {
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Report 全部依赖的检查结果, 任一依赖不可用或服务正在关闭时 Status 为 down
+type Report struct {
+	Status       string                 `json:"status"`
+	ShuttingDown bool                   `json:"shuttingDown,omitempty"`
+	Checks       map[string]CheckResult `json:"checks"`
+}
+
+var (
+	mu           sync.RWMutex
+	checks       []check
+	shuttingDown int32
+)
+
+// Register 注册就绪检查, timeout <= 0 时使用 DefaultTimeout
+func Register(name string, timeout time.Duration, fn CheckFunc) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	checks = append(checks, check{name: name, timeout: timeout, fn: fn})
+}
+
+// SetShuttingDown 标记服务正在关闭, 之后就绪检查始终失败, 负载均衡摘除流量
+func SetShuttingDown() {
+	atomic.StoreInt32(&shuttingDown, 1)
+}
+
+func IsShuttingDown() bool {
+	return atomic.LoadInt32(&shuttingDown) == 1
+}
+
+// Ready 并发执行全部就绪检查, 每个检查使用各自的超时时间
+func Ready(ctx context.Context) Report {
+	mu.RLock()
+	registered := make([]check, len(checks))
+	copy(registered, checks)
+	mu.RUnlock()
+
+	report := Report{
+		Status:       StatusUp,
+		ShuttingDown: IsShuttingDown(),
+		Checks:       make(map[string]CheckResult, len(registered)),
+	}
+
+	var (
+		wg        sync.WaitGroup
+		resultsMu sync.Mutex
+	)
+	for _, c := range registered {
+		wg.Add(1)
+		go func(c check) {
+			defer wg.Done()
+			result := c.run(ctx)
+
+			resultsMu.Lock()
+			defer resultsMu.Unlock()
+			report.Checks[c.name] = result
+			if result.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	if report.ShuttingDown {
+		report.Status = StatusDown
+	}
+	return report
+}
+
+func (c check) run(ctx context.Context) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.fn(ctx)
+	}()
+
+	// 检查函数未响应 ctx 时也按超时返回
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{Status: StatusUp, Duration: time.Since(start).String()}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,169 @@
+package lifecycle
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"gin-example/pkg/logging"
+)
+
+// Hook 关闭时执行的清理函数, 需要在 ctx 超时前返回
+type Hook func(ctx context.Context) error
+
+// Stage 关闭阶段, 阶段小的钩子先执行, 同一阶段内按注册的逆序执行
+type Stage int
+
+const (
+	// StageServer 停止接收新请求并等待处理中的请求完成
+	StageServer Stage = iota
+	// StageWorker 后台任务 (如过期 session 清理), 在其依赖的数据库, 缓存之前停止
+	StageWorker
+	// StageResource 数据库, 缓存等连接
+	StageResource
+	// StageLogger 最后刷新日志
+	StageLogger
+)
+
+type namedHook struct {
+	name  string
+	stage Stage
+	fn    Hook
+}
+
+var (
+	mu    sync.Mutex
+	hooks []namedHook
+
+	restart func() error
+
+	shuttingDown = make(chan struct{})
+	shutdownOnce sync.Once
+)
+
+// OnRestart 注册 SIGHUP 的处理函数, 通常启动新进程接管监听, 成功后当前进程按 SIGTERM 流程退出
+func OnRestart(fn func() error) {
+	mu.Lock()
+	defer mu.Unlock()
+	restart = fn
+}
+
+// OnShutdown 在 StageWorker 阶段注册关闭钩子
+func OnShutdown(name string, fn Hook) {
+	OnShutdownStage(name, StageWorker, fn)
+}
+
+// OnShutdownStage 注册关闭钩子, 关闭时按阶段执行, 同一阶段内按注册的逆序执行, 先注册的资源最后释放
+func OnShutdownStage(name string, stage Stage, fn Hook) {
+	mu.Lock()
+	defer mu.Unlock()
+	hooks = append(hooks, namedHook{name: name, stage: stage, fn: fn})
+}
+
+// ShuttingDown 开始关闭时该 channel 被关闭, 长连接处理函数 (如 api.Stream) 据此提前结束
+func ShuttingDown() <-chan struct{} {
+	return shuttingDown
+}
+
+// Wait 阻塞等待 SIGINT 或 SIGTERM, 收到后在 timeout 内执行全部关闭钩子, 关闭期间再次收到信号时立即退出.
+// 收到 SIGHUP 时执行 OnRestart 注册的函数, 成功后同样关闭当前进程.
+func Wait(timeout time.Duration) {
+	osSignal := make(chan os.Signal, 1)
+	signal.Notify(osSignal, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := range osSignal {
+		if sig != syscall.SIGHUP {
+			logging.Logger.Info("received signal, shutting down ...", zap.String("signal", sig.String()))
+			break
+		}
+
+		mu.Lock()
+		fn := restart
+		mu.Unlock()
+		if fn == nil {
+			logging.Logger.Warn("received SIGHUP but no restart handler registered, ignored")
+			continue
+		}
+		if err := fn(); err != nil {
+			logging.Logger.Error("restart failed, keep running", zap.Error(err))
+			continue
+		}
+		logging.Logger.Info("restarted, shutting down the old process ...")
+		break
+	}
+
+	go func() {
+		for sig := range osSignal {
+			if sig == syscall.SIGHUP {
+				continue
+			}
+			logging.Logger.Warn("received signal again, exit immediately", zap.String("signal", sig.String()))
+			_ = logging.Sync()
+			os.Exit(1)
+		}
+	}()
+
+	Shutdown(timeout)
+}
+
+// Shutdown 在 timeout 内按阶段及注册的逆序执行关闭钩子, 单个钩子失败不影响后续钩子执行
+func Shutdown(timeout time.Duration) {
+	shutdownOnce.Do(func() {
+		close(shuttingDown)
+
+		mu.Lock()
+		registered := make([]namedHook, len(hooks))
+		copy(registered, hooks)
+		mu.Unlock()
+		// 先逆序, 再按阶段稳定排序, 同一阶段内保持注册的逆序
+		for i, j := 0, len(registered)-1; i < j; i, j = i+1, j-1 {
+			registered[i], registered[j] = registered[j], registered[i]
+		}
+		sort.SliceStable(registered, func(i, j int) bool {
+			return registered[i].stage < registered[j].stage
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		for _, hook := range registered {
+			start := time.Now()
+			if err := runHook(ctx, hook.fn); err != nil {
+				logging.Logger.Error("shutdown hook failed", zap.String("hook", hook.name), zap.Error(err))
+				continue
+			}
+			logging.Logger.Info("shutdown hook completed",
+				zap.String("hook", hook.name), zap.Duration("elapsed", time.Since(start)))
+		}
+	})
+}
+
+// 超时后再等待钩子的时间, 使超时后才开始执行的钩子 (如刷新日志) 仍有机会完成
+const hookGrace = 100 * time.Millisecond
+
+// runHook 在单独的 goroutine 中执行钩子, 钩子不响应 ctx 时超时后不再等待, 记为失败后继续执行后续钩子,
+// 钩子的 goroutine 随进程退出
+func runHook(ctx context.Context, fn Hook) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(hookGrace):
+		return errors.Wrap(ctx.Err(), "hook did not return before the shutdown timeout")
+	}
+}
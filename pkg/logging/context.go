@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type contextKey int
+
+const requestIDKey contextKey = 0
+
+// WithRequestID 将请求 ID 保存到 ctx 中, 日志, gorm 等通过 ctx 获取
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext 获取 ctx 中的请求 ID, 不存在时返回空字符串
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// ContextFields 返回 ctx 中需要记录到日志的字段
+func ContextFields(ctx context.Context) []zap.Field {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		return []zap.Field{zap.String("request_id", requestID)}
+	}
+	return nil
+}
+
+// FromContext 返回带有 ctx 中请求 ID 的 Logger, 处理请求时使用它记录日志
+func FromContext(ctx context.Context) *zap.Logger {
+	return Logger.With(ContextFields(ctx)...)
+}
@@ -0,0 +1,159 @@
+package logging
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"gin-example/pkg/setting"
+)
+
+// 可在运行期间调整级别的日志
+const (
+	LoggerApp      = "app"
+	LoggerAccess   = "access"
+	LoggerGinRoute = "gin"
+	LoggerGorm     = "gorm"
+)
+
+// LevelStatus 日志当前级别, 临时修改的级别到期后恢复为 RestoreLevel
+type LevelStatus struct {
+	Logger       string     `json:"logger"`
+	Level        string     `json:"level"`
+	ExpireAt     *time.Time `json:"expireAt,omitempty"`
+	RestoreLevel string     `json:"restoreLevel,omitempty"`
+}
+
+type dynamicLevel struct {
+	zap.AtomicLevel
+
+	mu       sync.Mutex
+	timer    *time.Timer
+	expireAt time.Time
+	restore  zapcore.Level
+}
+
+func newDynamicLevel() *dynamicLevel {
+	return &dynamicLevel{AtomicLevel: zap.NewAtomicLevel()}
+}
+
+// set 修改日志级别, ttl > 0 时到期后恢复为临时修改之前的级别
+func (l *dynamicLevel) set(level zapcore.Level, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	} else if ttl > 0 {
+		l.restore = l.Level()
+	}
+	l.SetLevel(level)
+
+	if ttl > 0 {
+		var timer *time.Timer
+		timer = time.AfterFunc(ttl, func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			// 已被新的修改覆盖
+			if l.timer != timer {
+				return
+			}
+			l.SetLevel(l.restore)
+			l.timer = nil
+		})
+		l.timer = timer
+		l.expireAt = time.Now().Add(ttl)
+	}
+}
+
+func (l *dynamicLevel) status(name string) LevelStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	status := LevelStatus{Logger: name, Level: l.Level().String()}
+	if l.timer != nil {
+		expireAt := l.expireAt
+		status.ExpireAt = &expireAt
+		status.RestoreLevel = l.restore.String()
+	}
+	return status
+}
+
+var levels = map[string]*dynamicLevel{
+	LoggerApp:      newDynamicLevel(),
+	LoggerAccess:   newDynamicLevel(),
+	LoggerGinRoute: newDynamicLevel(),
+	LoggerGorm:     newDynamicLevel(),
+}
+
+// Levels 返回全部日志的当前级别
+func Levels() []LevelStatus {
+	statuses := make([]LevelStatus, 0, len(levels))
+	for name, level := range levels {
+		statuses = append(statuses, level.status(name))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Logger < statuses[j].Logger })
+	return statuses
+}
+
+// SetLevel 修改指定日志的级别, ttl > 0 时到期后自动恢复
+func SetLevel(logger string, level string, ttl time.Duration) error {
+	l, ok := levels[logger]
+	if !ok {
+		return errors.Errorf("unknown logger: %s", logger)
+	}
+	zapLevel, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	l.set(zapLevel, ttl)
+	Logger.Info("log level changed.",
+		zap.String("logger", logger), zap.String("level", zapLevel.String()), zap.Duration("ttl", ttl))
+	return nil
+}
+
+// applyLevelSetting 按配置设置全部日志级别, 同时取消临时修改
+func applyLevelSetting() {
+	loggerSetting := setting.GetLoggerSetting()
+	configured := map[string]string{
+		LoggerApp:      loggerSetting.Level,
+		LoggerAccess:   loggerSetting.AccessLevel,
+		LoggerGinRoute: loggerSetting.GinRouteLevel,
+		LoggerGorm:     loggerSetting.GormLevel,
+	}
+	for name, level := range configured {
+		if level == "" {
+			level = loggerSetting.Level
+		}
+		zapLevel, err := parseLevel(level)
+		if err != nil {
+			zapLevel = zapcore.InfoLevel
+		}
+		levels[name].set(zapLevel, 0)
+	}
+}
+
+func parseLevel(level string) (zapcore.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "warn", "warning":
+		return zapcore.WarnLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	case "panic":
+		return zapcore.PanicLevel, nil
+	case "fatal":
+		return zapcore.FatalLevel, nil
+	default:
+		return zapcore.InfoLevel, errors.Errorf("unknown log level: %s", level)
+	}
+}
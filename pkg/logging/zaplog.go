@@ -1,9 +1,10 @@
 package logging
 
 import (
+	"io"
 	"os"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -15,66 +16,125 @@ import (
 
 var (
 	Logger       *zap.Logger
+	AccessLogger *zap.Logger
 	routerLogger *zap.Logger
 	GormLogger   *zap.Logger
 )
 
 // Setup initialize the log instance
 func Setup() {
-	Logger = NewZapLogger(newAllFieldsZapCore())
-	Logger.Info("initialization zap logger ok.", zap.String("LogLever", switchLogLevel().String()))
+	applyLevelSetting()
+	loggerSetting := setting.GetLoggerSetting()
 
-	routerLogger = NewZapLogger(newGinDebugZapCore())
-	Logger.Info("initialization gin debug logger ok.", zap.String("LogLever", switchLogLevel().String()))
+	Logger = NewZapLogger(newZapCore(allFieldsEncoderConfig(), loggerSetting.App, levels[LoggerApp]))
+	Logger.Info("initialization zap logger ok.", zap.String("LogLever", levels[LoggerApp].String()))
 
-	GormLogger = NewZapWithoutCallerLogger(newGormZapCore())
-	Logger.Info("initialization zap logger with caller ok.", zap.String("LogLever", switchLogLevel().String()))
+	AccessLogger = NewZapWithoutCallerLogger(newZapCore(allFieldsEncoderConfig(), loggerSetting.Access, levels[LoggerAccess]))
+	Logger.Info("initialization access logger ok.", zap.String("LogLever", levels[LoggerAccess].String()))
+
+	routerLogger = NewZapLogger(newZapCore(ginDebugEncoderConfig(), loggerSetting.GinRoute, levels[LoggerGinRoute]))
+	Logger.Info("initialization gin debug logger ok.", zap.String("LogLever", levels[LoggerGinRoute].String()))
+
+	GormLogger = NewZapWithoutCallerLogger(newZapCore(gormEncoderConfig(), loggerSetting.Gorm, levels[LoggerGorm]))
+	Logger.Info("initialization zap logger with caller ok.", zap.String("LogLever", levels[LoggerGorm].String()))
+
+	// 日志级别支持热加载, 输出位置修改后需要重启生效
+	setting.Subscribe(setting.SectionLog, func() {
+		applyLevelSetting()
+		Logger.Info("log level reloaded.", zap.Any("levels", Levels()))
+	})
+}
+
+// SetupNop 不输出任何日志, 用于输出结果到 stdout 的命令, 避免日志混入结果
+func SetupNop() {
+	Logger = zap.NewNop()
+	AccessLogger = zap.NewNop()
+	routerLogger = zap.NewNop()
+	GormLogger = zap.NewNop()
 }
 
-func switchLogLevel() zapcore.Level {
-	switch strings.ToLower(setting.ServerSetting.RunMode) {
-	case "debug":
-		return zapcore.DebugLevel
-	case "release":
-		return zapcore.InfoLevel
+var (
+	writersMu sync.Mutex
+	// 按文件路径共享 lumberjack.Logger, 多个日志写同一个文件时只有一个实例负责轮转
+	fileWriters = make(map[string]zapcore.WriteSyncer)
+)
+
+// switchLogOutput 返回 sink 配置的全部输出, 未配置 Outputs 时按 Log.Stdout, Log.FilePath 输出
+func switchLogOutput(sink setting.LogSink) zapcore.WriteSyncer {
+	outputs := sink.Outputs
+	if len(outputs) == 0 {
+		loggerSetting := setting.GetLoggerSetting()
+		if loggerSetting.Stdout {
+			outputs = []setting.LogOutput{{Type: "stdout"}}
+		} else {
+			outputs = []setting.LogOutput{{Type: "file", FilePath: loggerSetting.FilePath, Compress: true}}
+		}
 	}
 
-	switch strings.ToLower(setting.LoggerSetting.Level) {
-	case "debug":
-		return zapcore.DebugLevel
-	case "info":
-		return zapcore.InfoLevel
-	case "warn", "warning":
-		return zapcore.WarnLevel
-	case "error":
-		return zapcore.ErrorLevel
-	case "panic":
-		return zapcore.PanicLevel
-	case "fatal":
-		return zapcore.FatalLevel
-	default:
-		return zapcore.InfoLevel
+	writers := make([]zapcore.WriteSyncer, 0, len(outputs))
+	for _, output := range outputs {
+		switch output.Type {
+		case "stdout":
+			writers = append(writers, consoleWriter(os.Stdout))
+		case "stderr":
+			writers = append(writers, consoleWriter(os.Stderr))
+		case "file":
+			writers = append(writers, fileWriter(output))
+		}
 	}
+	return zapcore.NewMultiWriteSyncer(writers...)
 }
 
-func switchLogOutput() zapcore.WriteSyncer {
-	if setting.LoggerSetting.Stdout {
-		return zapcore.AddSync(os.Stdout)
-	}
+// consoleWriter 标准输出不带缓冲, 且终端或管道上 fsync 会返回 EINVAL, 因此 Sync 为空操作
+func consoleWriter(f *os.File) zapcore.WriteSyncer {
+	return zapcore.Lock(zapcore.AddSync(struct{ io.Writer }{f}))
+}
+
+func fileWriter(output setting.LogOutput) zapcore.WriteSyncer {
+	writersMu.Lock()
+	defer writersMu.Unlock()
 
-	return zapcore.AddSync(&lumberjack.Logger{
-		Filename:   setting.LoggerSetting.FilePath, // 日志文件路径
-		MaxSize:    128,                            // 每个日志文件保存的最大尺寸 单位：M
-		MaxBackups: 30,                             // 日志文件最多保存多少个备份
-		MaxAge:     7,                              // 文件最多保存多少天
-		Compress:   true,                           // 是否压缩
+	if w, ok := fileWriters[output.FilePath]; ok {
+		return w
+	}
+	w := zapcore.AddSync(&lumberjack.Logger{
+		Filename:   output.FilePath,                       // 日志文件路径
+		MaxSize:    valueOrDefault(output.MaxSize, 128),   // 每个日志文件保存的最大尺寸 单位：M
+		MaxBackups: valueOrDefault(output.MaxBackups, 30), // 日志文件最多保存多少个备份
+		MaxAge:     valueOrDefault(output.MaxAge, 7),      // 文件最多保存多少天
+		Compress:   output.Compress,                       // 是否压缩
 	})
+	fileWriters[output.FilePath] = w
+	return w
 }
 
+func valueOrDefault(value, defaultValue int) int {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// ISO8601TimeEncoder 本地时间, 格式 2006-01-02 15:04:05.000, 对应 TimeFormat: datetime
 func ISO8601TimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
 	enc.AppendString(t.Format("2006-01-02 15:04:05.000"))
 }
 
+func switchTimeEncoder(timeFormat string) zapcore.TimeEncoder {
+	switch timeFormat {
+	case "datetime":
+		return ISO8601TimeEncoder
+	case "rfc3339":
+		return zapcore.RFC3339TimeEncoder
+	case "epoch":
+		return zapcore.EpochTimeEncoder
+	case "epochmillis":
+		return zapcore.EpochMillisTimeEncoder
+	default:
+		return zapcore.ISO8601TimeEncoder // ISO8601 UTC 时间格式
+	}
+}
+
 func NewZapLogger(core zapcore.Core) *zap.Logger {
 	return zap.New(core, zap.AddCaller())
 }
@@ -83,59 +143,62 @@ func NewZapWithoutCallerLogger(core zapcore.Core) *zap.Logger {
 	return zap.New(core)
 }
 
-func newAllFieldsZapCore() zapcore.Core {
+func newZapCore(encoderConfig zapcore.EncoderConfig, sink setting.LogSink, level zapcore.LevelEnabler) zapcore.Core {
+	encoderConfig.EncodeTime = switchTimeEncoder(sink.TimeFormat)
+
+	var encoder zapcore.Encoder
+	switch sink.Encoder {
+	case "console":
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	}
+
 	return zapcore.NewCore(
-		zapcore.NewJSONEncoder(zapcore.EncoderConfig{
-			NameKey:        "name",
-			TimeKey:        "@timestamp",
-			LevelKey:       "level",
-			CallerKey:      "caller",
-			MessageKey:     "message",
-			StacktraceKey:  "stacktrace",
-			LineEnding:     zapcore.DefaultLineEnding,
-			EncodeLevel:    zapcore.LowercaseLevelEncoder,  // 小写编码器
-			EncodeTime:     zapcore.ISO8601TimeEncoder,     // ISO8601 UTC 时间格式
-			EncodeDuration: zapcore.SecondsDurationEncoder, //
-			EncodeCaller:   zapcore.ShortCallerEncoder,     // 全路径编码器
-			EncodeName:     zapcore.FullNameEncoder,
-		}), // 编码器配置
-		zapcore.NewMultiWriteSyncer(switchLogOutput()), // 打印到控制台或文件
-		zap.NewAtomicLevelAt(switchLogLevel()),         // 日志级别
+		encoder,               // 编码器配置
+		switchLogOutput(sink), // 打印到控制台或文件
+		level,                 // 日志级别
 	)
 }
 
-func newGinDebugZapCore() zapcore.Core {
-	return zapcore.NewCore(
-		zapcore.NewJSONEncoder(zapcore.EncoderConfig{
-			LineEnding:     zapcore.DefaultLineEnding,
-			EncodeLevel:    zapcore.LowercaseLevelEncoder,  // 小写编码器
-			EncodeTime:     zapcore.ISO8601TimeEncoder,     // ISO8601 UTC 时间格式
-			EncodeDuration: zapcore.SecondsDurationEncoder, //
-			EncodeCaller:   zapcore.ShortCallerEncoder,     // 全路径编码器
-			EncodeName:     zapcore.FullNameEncoder,
-		}), // 编码器配置
-		zapcore.NewMultiWriteSyncer(switchLogOutput()), // 打印到控制台或文件
-		zap.NewAtomicLevelAt(switchLogLevel()),         // 日志级别
-	)
+func allFieldsEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		NameKey:        "name",
+		TimeKey:        "@timestamp",
+		LevelKey:       "level",
+		CallerKey:      "caller",
+		MessageKey:     "message",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,  // 小写编码器
+		EncodeDuration: zapcore.SecondsDurationEncoder, //
+		EncodeCaller:   zapcore.ShortCallerEncoder,     // 全路径编码器
+		EncodeName:     zapcore.FullNameEncoder,
+	}
 }
 
-func newGormZapCore() zapcore.Core {
-	return zapcore.NewCore(
-		zapcore.NewJSONEncoder(zapcore.EncoderConfig{
-			NameKey:        "name",
-			TimeKey:        "@timestamp",
-			LevelKey:       "level",
-			MessageKey:     "message",
-			LineEnding:     zapcore.DefaultLineEnding,
-			EncodeLevel:    zapcore.LowercaseLevelEncoder,  // 小写编码器
-			EncodeTime:     zapcore.ISO8601TimeEncoder,     // ISO8601 UTC 时间格式
-			EncodeDuration: zapcore.SecondsDurationEncoder, //
-			EncodeCaller:   zapcore.ShortCallerEncoder,     // 全路径编码器
-			EncodeName:     zapcore.FullNameEncoder,
-		}), // 编码器配置
-		zapcore.NewMultiWriteSyncer(switchLogOutput()), // 打印到控制台或文件
-		zap.NewAtomicLevelAt(switchLogLevel()),         // 日志级别
-	)
+func ginDebugEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,  // 小写编码器
+		EncodeDuration: zapcore.SecondsDurationEncoder, //
+		EncodeCaller:   zapcore.ShortCallerEncoder,     // 全路径编码器
+		EncodeName:     zapcore.FullNameEncoder,
+	}
+}
+
+func gormEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		NameKey:        "name",
+		TimeKey:        "@timestamp",
+		LevelKey:       "level",
+		MessageKey:     "message",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,  // 小写编码器
+		EncodeDuration: zapcore.SecondsDurationEncoder, //
+		EncodeCaller:   zapcore.ShortCallerEncoder,     // 全路径编码器
+		EncodeName:     zapcore.FullNameEncoder,
+	}
 }
 
 // print gin route function
@@ -144,3 +207,17 @@ func GinDebugPrintRouteZapLoggerFunc(httpMethod, absolutePath, handlerName strin
 		zap.Strings("Gin Debug", []string{httpMethod, absolutePath, handlerName, strconv.Itoa(nuHandlers)}),
 	)
 }
+
+// Sync 刷新全部日志的缓冲, 进程退出前调用
+func Sync() error {
+	var err error
+	for _, logger := range []*zap.Logger{Logger, AccessLogger, routerLogger, GormLogger} {
+		if logger == nil {
+			continue
+		}
+		if e := logger.Sync(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
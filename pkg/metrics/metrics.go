@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "ginex"
+
+// Registry 全部指标注册在独立的 Registry 中, 不依赖 prometheus 全局默认值
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Total number of HTTP requests by route template, method and status.",
+	}, []string{"route", "method", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route template, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	SessionsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "session",
+		Name:      "created_total",
+		Help:      "Total number of login sessions created.",
+	})
+
+	SessionsDestroyed = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "session",
+		Name:      "destroyed_total",
+		Help:      "Total number of login sessions destroyed.",
+	})
+
+	dbReplicaUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "replica_up",
+		Help:      "Whether the database read replica is healthy and in rotation.",
+	}, []string{"replica"})
+
+	cacheRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "requests_total",
+		Help:      "Total number of cache reads by result: hit, miss or error.",
+	}, []string{"result"})
+
+	UploadBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "upload",
+		Name:      "bytes_total",
+		Help:      "Total number of bytes of uploaded files.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		httpRequestsTotal,
+		httpRequestDuration,
+		SessionsCreated,
+		SessionsDestroyed,
+		UploadBytes,
+		dbReplicaUp,
+		cacheRequestsTotal,
+	)
+}
+
+// ObserveHTTPRequest 记录一次 HTTP 请求, route 为 gin 路由模板, 如 /api/v1/tags/:id
+func ObserveHTTPRequest(route, method string, status int, elapsed time.Duration) {
+	statusCode := strconv.Itoa(status)
+	httpRequestsTotal.WithLabelValues(route, method, statusCode).Inc()
+	httpRequestDuration.WithLabelValues(route, method, statusCode).Observe(elapsed.Seconds())
+}
+
+// SetReplicaUp 记录数据库只读副本是否可用
+func SetReplicaUp(replica string, up bool) {
+	value := 0.0
+	if up {
+		value = 1
+	}
+	dbReplicaUp.WithLabelValues(replica).Set(value)
+}
+
+// Handler 返回 /metrics 接口, 本地可以直接 curl 查看
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveCache 记录一次缓存读取, result 为 hit, miss 或 error
+func ObserveCache(result string) {
+	cacheRequestsTotal.WithLabelValues(result).Inc()
+}
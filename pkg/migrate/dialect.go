@@ -0,0 +1,190 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Dialect 不同数据库的加锁方式及历史表语句
+type Dialect interface {
+	Name() string
+	// Lock 在 conn 上获取咨询锁, 同一时间只有一个实例执行 Migration
+	Lock(ctx context.Context, conn *sql.Conn, name string, timeout time.Duration) error
+	Unlock(ctx context.Context, conn *sql.Conn, name string) error
+	// HasTable 当前数据库中是否存在 table
+	HasTable(ctx context.Context, conn *sql.Conn, table string) (bool, error)
+	// HasColumn table 中是否存在 column
+	HasColumn(ctx context.Context, conn *sql.Conn, table, column string) (bool, error)
+	// CreateHistoryTable 创建历史表的语句
+	CreateHistoryTable(table string) string
+	// Placeholder 第 n 个参数的占位符, 从 1 开始
+	Placeholder(n int) string
+	// TransactionalDDL 是否支持在事务中执行 DDL
+	TransactionalDDL() bool
+}
+
+var dialects = map[string]Dialect{
+	"mysql":    mysqlDialect{},
+	"postgres": postgresDialect{},
+	"sqlite":   sqliteDialect{},
+}
+
+// GetDialect 返回 name 对应的 Dialect
+func GetDialect(name string) (Dialect, error) {
+	d, ok := dialects[name]
+	if !ok {
+		return nil, errors.Errorf("unsupported migration dialect: %s", name)
+	}
+	return d, nil
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+func (mysqlDialect) Lock(ctx context.Context, conn *sql.Conn, name string, timeout time.Duration) error {
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, int(timeout/time.Second)).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		return errors.Errorf("acquire migration lock %s timeout after %s", name, timeout)
+	}
+	return nil
+}
+
+func (mysqlDialect) Unlock(ctx context.Context, conn *sql.Conn, name string) error {
+	_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", name)
+	return err
+}
+
+func (mysqlDialect) HasTable(ctx context.Context, conn *sql.Conn, table string) (bool, error) {
+	var count int
+	err := conn.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?", table).Scan(&count)
+	return count > 0, err
+}
+
+func (mysqlDialect) HasColumn(ctx context.Context, conn *sql.Conn, table, column string) (bool, error) {
+	var count int
+	err := conn.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?",
+		table, column).Scan(&count)
+	return count > 0, err
+}
+
+func (mysqlDialect) CreateHistoryTable(table string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` (\n"+
+		"  `version` bigint unsigned NOT NULL,\n"+
+		"  `name` varchar(255) NOT NULL,\n"+
+		"  `checksum` char(64) NOT NULL,\n"+
+		"  `applied_at` datetime(3) NOT NULL,\n"+
+		"  `execution_ms` bigint NOT NULL,\n"+
+		"  PRIMARY KEY (`version`)\n"+
+		")", table)
+}
+
+func (mysqlDialect) Placeholder(int) string { return "?" }
+
+// MySQL 的 DDL 会隐式提交事务
+func (mysqlDialect) TransactionalDDL() bool { return false }
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+// lockKey pg_advisory_lock 使用 bigint 作为锁名
+func (postgresDialect) lockKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+func (d postgresDialect) Lock(ctx context.Context, conn *sql.Conn, name string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", d.lockKey(name)); err != nil {
+		if ctx.Err() != nil {
+			return errors.Errorf("acquire migration lock %s timeout after %s", name, timeout)
+		}
+		return err
+	}
+	return nil
+}
+
+func (d postgresDialect) Unlock(ctx context.Context, conn *sql.Conn, name string) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", d.lockKey(name))
+	return err
+}
+
+func (postgresDialect) HasTable(ctx context.Context, conn *sql.Conn, table string) (bool, error) {
+	var count int
+	err := conn.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = CURRENT_SCHEMA() AND table_name = $1", table).Scan(&count)
+	return count > 0, err
+}
+
+func (postgresDialect) HasColumn(ctx context.Context, conn *sql.Conn, table, column string) (bool, error) {
+	var count int
+	err := conn.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = CURRENT_SCHEMA() AND table_name = $1 AND column_name = $2",
+		table, column).Scan(&count)
+	return count > 0, err
+}
+
+func (postgresDialect) CreateHistoryTable(table string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" (
+  version bigint NOT NULL PRIMARY KEY,
+  name varchar(255) NOT NULL,
+  checksum char(64) NOT NULL,
+  applied_at timestamptz NOT NULL,
+  execution_ms bigint NOT NULL
+)`, table)
+}
+
+func (postgresDialect) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+
+func (postgresDialect) TransactionalDDL() bool { return true }
+
+// sqliteDialect sqlite 只用于本地单实例, 写操作由数据库文件锁串行化, 不需要咨询锁
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+func (sqliteDialect) Lock(context.Context, *sql.Conn, string, time.Duration) error { return nil }
+
+func (sqliteDialect) Unlock(context.Context, *sql.Conn, string) error { return nil }
+
+func (sqliteDialect) HasTable(ctx context.Context, conn *sql.Conn, table string) (bool, error) {
+	var count int
+	err := conn.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&count)
+	return count > 0, err
+}
+
+func (sqliteDialect) HasColumn(ctx context.Context, conn *sql.Conn, table, column string) (bool, error) {
+	var count int
+	err := conn.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&count)
+	return count > 0, err
+}
+
+func (sqliteDialect) CreateHistoryTable(table string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" (
+  version integer NOT NULL PRIMARY KEY,
+  name varchar(255) NOT NULL,
+  checksum char(64) NOT NULL,
+  applied_at datetime NOT NULL,
+  execution_ms integer NOT NULL
+)`, table)
+}
+
+func (sqliteDialect) Placeholder(int) string { return "?" }
+
+func (sqliteDialect) TransactionalDDL() bool { return true }
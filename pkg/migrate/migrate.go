@@ -0,0 +1,336 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	historyTable = "schema_migrations"
+	lockName     = "schema_migrations"
+)
+
+// 迁移状态
+const (
+	StatusApplied          = "applied"
+	StatusPending          = "pending"
+	StatusChecksumMismatch = "checksum mismatch"
+	// 数据库中已执行, 但当前程序中不存在的版本, 通常是更新版本的程序执行过
+	StatusUnknown = "unknown"
+)
+
+// Options 执行选项
+type Options struct {
+	TablePrefix string
+	// DryRun 只输出将要执行的 SQL, 不修改数据库
+	DryRun bool
+	// Out 输出执行的 SQL 及进度, 为空时不输出
+	Out io.Writer
+	// LockTimeout 等待其他实例释放锁的时间
+	LockTimeout time.Duration
+}
+
+// Status 单个版本的状态
+type Status struct {
+	Version   uint
+	Name      string
+	Status    string
+	AppliedAt *time.Time
+}
+
+type applied struct {
+	version   uint
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Migrator 在一个独占的数据库连接上执行 Migration
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+	options    Options
+}
+
+// New 使用 dialect 注册的全部 Migration 创建 Migrator
+func New(db *sql.DB, dialectName string, options Options) (*Migrator, error) {
+	dialect, err := GetDialect(dialectName)
+	if err != nil {
+		return nil, err
+	}
+	if options.Out == nil {
+		options.Out = ioutil.Discard
+	}
+	if options.LockTimeout <= 0 {
+		options.LockTimeout = time.Minute
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: Migrations(dialectName), options: options}, nil
+}
+
+func (m *Migrator) historyTable() string {
+	return m.options.TablePrefix + historyTable
+}
+
+// withConn 在独占连接上执行 fn, lock 为 true 时先获取咨询锁并创建历史表.
+// DryRun 时不加锁也不创建历史表, 不修改数据库.
+func (m *Migrator) withConn(ctx context.Context, lock bool, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if !lock || m.options.DryRun {
+		return fn(conn)
+	}
+
+	name := m.options.TablePrefix + lockName
+	if err := m.dialect.Lock(ctx, conn, name, m.options.LockTimeout); err != nil {
+		return err
+	}
+	defer func() {
+		_ = m.dialect.Unlock(context.Background(), conn, name)
+	}()
+
+	if _, err := conn.ExecContext(ctx, m.dialect.CreateHistoryTable(m.historyTable())); err != nil {
+		return errors.Wrap(err, "create migration history table")
+	}
+	return fn(conn)
+}
+
+// loadApplied 读取历史表, 历史表不存在时返回空
+func (m *Migrator) loadApplied(ctx context.Context, conn *sql.Conn) (map[uint]applied, error) {
+	result := make(map[uint]applied)
+	exists, err := m.dialect.HasTable(ctx, conn, m.historyTable())
+	if err != nil || !exists {
+		return result, err
+	}
+
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT version, name, checksum, applied_at FROM %s", m.historyTable()))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		var a applied
+		if err := rows.Scan(&a.version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		result[a.version] = a
+	}
+	return result, rows.Err()
+}
+
+// verify 已执行的 Migration 被修改时拒绝继续执行
+func (m *Migrator) verify(history map[uint]applied) error {
+	for _, migration := range m.migrations {
+		a, ok := history[migration.Version]
+		if ok && a.checksum != migration.Checksum() {
+			return errors.Errorf("checksum mismatch of applied migration %d %s, add a new migration instead of modifying it",
+				migration.Version, migration.Name)
+		}
+	}
+	return nil
+}
+
+// Up 依次执行未执行的 Migration, target 为 0 时执行到最新版本
+func (m *Migrator) Up(ctx context.Context, target uint) error {
+	return m.withConn(ctx, true, func(conn *sql.Conn) error {
+		history, err := m.loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(history); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if target > 0 && migration.Version > target {
+				break
+			}
+			if _, ok := history[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down 按版本号倒序回滚最近执行的 steps 个 Migration
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withConn(ctx, true, func(conn *sql.Conn) error {
+		history, err := m.loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(history); err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := history[migration.Version]; !ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, false); err != nil {
+				return err
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// apply 执行 Up 或 Down 脚本并更新历史表, 支持 DDL 事务的数据库在同一个事务中执行
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, up bool) error {
+	script, direction := migration.Up, "up"
+	if !up {
+		script, direction = migration.Down, "down"
+	}
+	stmts := statements(script, m.options.TablePrefix)
+	if up {
+		columns, err := m.missingColumns(ctx, conn, migration)
+		if err != nil {
+			return errors.Wrapf(err, "migrate %s %d %s", direction, migration.Version, migration.Name)
+		}
+		stmts = append(stmts, columns...)
+	}
+
+	var (
+		record string
+		args   []interface{}
+	)
+	start := time.Now()
+	if up {
+		record = fmt.Sprintf("INSERT INTO %s (version, name, checksum, applied_at, execution_ms) VALUES (%s, %s, %s, %s, %s)",
+			m.historyTable(), m.dialect.Placeholder(1), m.dialect.Placeholder(2), m.dialect.Placeholder(3),
+			m.dialect.Placeholder(4), m.dialect.Placeholder(5))
+	} else {
+		record = fmt.Sprintf("DELETE FROM %s WHERE version = %s", m.historyTable(), m.dialect.Placeholder(1))
+		args = []interface{}{migration.Version}
+	}
+
+	fmt.Fprintf(m.options.Out, "-- %s %d %s\n", direction, migration.Version, migration.Name)
+	if m.options.DryRun {
+		for _, stmt := range stmts {
+			fmt.Fprintf(m.options.Out, "%s\n", withSemicolon(stmt))
+		}
+		fmt.Fprintf(m.options.Out, "%s;\n", record)
+		return nil
+	}
+
+	var execer interface {
+		ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	} = conn
+	var tx *sql.Tx
+	if m.dialect.TransactionalDDL() {
+		var err error
+		if tx, err = conn.BeginTx(ctx, nil); err != nil {
+			return err
+		}
+		execer = tx
+	}
+	rollback := func(err error) error {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+		return errors.Wrapf(err, "migrate %s %d %s", direction, migration.Version, migration.Name)
+	}
+
+	for _, stmt := range stmts {
+		if _, err := execer.ExecContext(ctx, stmt); err != nil {
+			return rollback(errors.Wrapf(err, "exec %q", stmt))
+		}
+	}
+	if up {
+		args = []interface{}{migration.Version, migration.Name, migration.Checksum(), time.Now(), time.Since(start).Milliseconds()}
+	}
+	if _, err := execer.ExecContext(ctx, record, args...); err != nil {
+		return rollback(err)
+	}
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return rollback(err)
+		}
+	}
+	fmt.Fprintf(m.options.Out, "-- %s %d %s completed in %s\n", direction, migration.Version, migration.Name, time.Since(start))
+	return nil
+}
+
+// missingColumns 执行 Up 前检查 Columns, 表已存在且缺少该列时返回添加列的语句;
+// 表不存在时由 Up 创建完整的表, 不需要补充
+func (m *Migrator) missingColumns(ctx context.Context, conn *sql.Conn, migration Migration) ([]string, error) {
+	var stmts []string
+	for _, column := range migration.Columns {
+		table := m.options.TablePrefix + column.Table
+		exists, err := m.dialect.HasTable(ctx, conn, table)
+		if err != nil {
+			return nil, errors.Wrapf(err, "check table %s", table)
+		}
+		if !exists {
+			continue
+		}
+		if exists, err = m.dialect.HasColumn(ctx, conn, table, column.Name); err != nil {
+			return nil, errors.Wrapf(err, "check column %s.%s", table, column.Name)
+		}
+		if !exists {
+			stmts = append(stmts, statements(column.Add, m.options.TablePrefix)...)
+		}
+	}
+	return stmts, nil
+}
+
+func withSemicolon(stmt string) string {
+	if len(stmt) > 0 && stmt[len(stmt)-1] == ';' {
+		return stmt
+	}
+	return stmt + ";"
+}
+
+// Status 返回全部 Migration 及数据库中已执行版本的状态
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withConn(ctx, false, func(conn *sql.Conn) error {
+		history, err := m.loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name, Status: StatusPending}
+			if a, ok := history[migration.Version]; ok {
+				appliedAt := a.appliedAt
+				status.AppliedAt = &appliedAt
+				status.Status = StatusApplied
+				if a.checksum != migration.Checksum() {
+					status.Status = StatusChecksumMismatch
+				}
+				delete(history, migration.Version)
+			}
+			statuses = append(statuses, status)
+		}
+		for _, a := range history {
+			appliedAt := a.appliedAt
+			statuses = append(statuses, Status{Version: a.version, Name: a.name, Status: StatusUnknown, AppliedAt: &appliedAt})
+		}
+		sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+		return nil
+	})
+	return statuses, err
+}
@@ -0,0 +1,98 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Migration 一个版本的表结构变更, Up, Down 中的 {prefix} 在执行时替换为 Database.TablePrefix.
+// 多条语句以行尾的 ; 分隔. 已执行的 Migration 不能再修改, 需要新增版本.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+	// Columns Up 之前已存在的表 (如 AutoMigrate 创建) 不受 CREATE TABLE IF NOT EXISTS 影响, 缺少的列在此补充
+	Columns []Column
+}
+
+// Column 执行 Up 前表已存在且没有 Name 列时, 在 Up 之后执行 Add 添加该列
+type Column struct {
+	// Table 不含前缀的表名
+	Table string
+	Name  string
+	// Add 添加列的语句, {prefix} 同 Up
+	Add string
+}
+
+// Checksum Up 脚本的 sha256, 用于发现已执行的脚本被修改
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]map[uint]Migration)
+)
+
+// Register 注册 dialect 对应的 Migration, 版本号重复时 panic, 在各 migrations 包的 init 中调用
+func Register(dialect string, m Migration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if m.Version == 0 {
+		panic("migration version must be greater than 0")
+	}
+	if registry[dialect] == nil {
+		registry[dialect] = make(map[uint]Migration)
+	}
+	if _, ok := registry[dialect][m.Version]; ok {
+		panic(errors.Errorf("migration version exist: %s %d, use other", dialect, m.Version))
+	}
+	registry[dialect][m.Version] = m
+}
+
+// Migrations 按版本号升序返回 dialect 的全部 Migration
+func Migrations(dialect string) []Migration {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	migrations := make([]Migration, 0, len(registry[dialect]))
+	for _, m := range registry[dialect] {
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations
+}
+
+// statements 替换表前缀, 按行尾的 ; 拆分为多条语句
+func statements(script, tablePrefix string) []string {
+	script = strings.ReplaceAll(script, "{prefix}", tablePrefix)
+
+	var (
+		result  []string
+		current strings.Builder
+	)
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			result = append(result, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		result = append(result, rest)
+	}
+	return result
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// 继承监听的约定与 systemd socket activation 一致:
+// 从 fd 3 开始的 LISTEN_FDS 个文件描述符, LISTEN_FDNAMES 为冒号分隔的名称
+const (
+	envListenFds     = "LISTEN_FDS"
+	envListenFdNames = "LISTEN_FDNAMES"
+	envListenPid     = "LISTEN_PID"
+	listenFdsStart   = 3
+
+	// Restart 传给新进程的管道写端, 新进程的监听就绪后写入 readyMessage
+	envReadyFd   = "LISTEN_READY_FD"
+	readyMessage = "ready"
+)
+
+type inheritedListener struct {
+	name     string
+	listener net.Listener
+}
+
+var (
+	inheritOnce sync.Once
+	inheritErr  error
+	inherited   []*inheritedListener
+)
+
+// loadInherited 读取父进程或 systemd 传入的监听, 读取后清除环境变量避免传给子进程
+func loadInherited() error {
+	inheritOnce.Do(func() {
+		defer func() {
+			_ = os.Unsetenv(envListenFds)
+			_ = os.Unsetenv(envListenFdNames)
+			_ = os.Unsetenv(envListenPid)
+		}()
+
+		fds := os.Getenv(envListenFds)
+		if fds == "" {
+			return
+		}
+		// systemd 设置 LISTEN_PID, 重新执行自身时不设置
+		if pid := os.Getenv(envListenPid); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+			return
+		}
+		count, err := strconv.Atoi(fds)
+		if err != nil {
+			inheritErr = errors.Wrapf(err, "invalid %s", envListenFds)
+			return
+		}
+		names := strings.Split(os.Getenv(envListenFdNames), ":")
+
+		for i := 0; i < count; i++ {
+			fd := listenFdsStart + i
+			syscall.CloseOnExec(fd)
+
+			name := "unknown"
+			if i < len(names) && names[i] != "" {
+				name = names[i]
+			}
+			f := os.NewFile(uintptr(fd), name)
+			ln, err := net.FileListener(f)
+			_ = f.Close()
+			if err != nil {
+				inheritErr = errors.Wrapf(err, "inherit listener fd %d", fd)
+				return
+			}
+			inherited = append(inherited, &inheritedListener{name: name, listener: ln})
+		}
+	})
+	return inheritErr
+}
+
+// notifyReady 由 Restart 启动的进程通知父进程监听已就绪, 其他情况不做处理
+func notifyReady() error {
+	fds := os.Getenv(envReadyFd)
+	if fds == "" {
+		return nil
+	}
+	_ = os.Unsetenv(envReadyFd)
+
+	fd, err := strconv.Atoi(fds)
+	if err != nil {
+		return errors.Wrapf(err, "invalid %s", envReadyFd)
+	}
+	f := os.NewFile(uintptr(fd), "ready")
+	defer f.Close()
+	if _, err := f.WriteString(readyMessage); err != nil {
+		return errors.Wrap(err, "write ready pipe")
+	}
+	return nil
+}
+
+// waitReady 等待新进程写入 readyMessage, 新进程退出时返回 EOF, 超时返回 os.ErrDeadlineExceeded
+func waitReady(r *os.File, timeout time.Duration) error {
+	if err := r.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return errors.Wrap(err, "set ready pipe deadline")
+	}
+	buf := make([]byte, len(readyMessage))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return errors.Wrap(err, "wait ready pipe")
+	}
+	if string(buf) != readyMessage {
+		return errors.Errorf("unexpected ready message %q", buf)
+	}
+	return nil
+}
+
+// takeInherited 按名称或地址取出继承的监听, 取出后不再复用
+func takeInherited(name, network, addr string) net.Listener {
+	for i, l := range inherited {
+		if l == nil {
+			continue
+		}
+		if l.name == name || sameAddr(l.listener.Addr(), network, addr) {
+			inherited[i] = nil
+			return l.listener
+		}
+	}
+	return nil
+}
+
+func sameAddr(a net.Addr, network, addr string) bool {
+	if a.Network() != network {
+		return false
+	}
+	if network == "unix" {
+		return a.String() == addr
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	_, inheritedPort, err := net.SplitHostPort(a.String())
+	return err == nil && port == inheritedPort
+}
+
+// listen 优先使用继承的监听, 没有时新建; unix socket 会先删除残留的 socket 文件
+func listen(name, network, addr string, mode os.FileMode) (net.Listener, error) {
+	if err := loadInherited(); err != nil {
+		return nil, err
+	}
+	if ln := takeInherited(name, network, addr); ln != nil {
+		return ln, nil
+	}
+
+	if network == "unix" {
+		if err := os.Remove(addr); err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrap(err, "remove stale unix socket")
+		}
+	}
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if network == "unix" && mode != 0 {
+		if err := os.Chmod(addr, mode); err != nil {
+			_ = ln.Close()
+			return nil, errors.Wrap(err, "chmod unix socket")
+		}
+	}
+	return ln, nil
+}
+
+// listenerFile 复制监听的文件描述符, 用于传给子进程
+func listenerFile(ln net.Listener) (*os.File, error) {
+	switch l := ln.(type) {
+	case *net.TCPListener:
+		return l.File()
+	case *net.UnixListener:
+		// 父进程关闭监听时不删除 socket 文件, 子进程仍在使用
+		l.SetUnlinkOnClose(false)
+		return l.File()
+	default:
+		return nil, errors.Errorf("unsupported listener type %T", ln)
+	}
+}
@@ -0,0 +1,235 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"gin-example/pkg/logging"
+	"gin-example/pkg/setting"
+)
+
+// 监听名称, 与 systemd socket 单元的 FileDescriptorName 对应
+const (
+	ListenerHTTP     = "http"
+	ListenerHTTPS    = "https"
+	ListenerRedirect = "redirect"
+)
+
+type entry struct {
+	name     string
+	srv      *http.Server
+	listener net.Listener
+	tls      bool
+}
+
+// Server 管理全部监听: 未开启 TLS 时只有 HttpPort 或 UnixSocket,
+// 开启 TLS 时 HttpsPort 提供服务, HttpPort 按 RedirectHTTP 跳转到 HTTPS.
+// 监听优先使用父进程或 systemd 传入的文件描述符.
+type Server struct {
+	entries   []*entry
+	stopWatch func()
+}
+
+// New 按 ServerSetting 创建服务器并监听端口, 证书无法加载或端口无法监听时返回错误
+func New(handler http.Handler) (*Server, error) {
+	serverSetting := setting.ServerSetting
+	s := &Server{stopWatch: func() {}}
+
+	if !serverSetting.TLS.Enable {
+		network, addr := mainAddr(":" + serverSetting.HttpPort)
+		if err := s.add(ListenerHTTP, network, addr, newHTTPServer(handler), false); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+
+	reloader, err := NewCertReloader(serverSetting.TLS.CertFile, serverSetting.TLS.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := NewTLSConfig(serverSetting.TLS, reloader)
+	if err != nil {
+		return nil, err
+	}
+	httpsServer := newHTTPServer(handler)
+	httpsServer.TLSConfig = tlsConfig
+	network, addr := mainAddr(":" + serverSetting.TLS.HttpsPort)
+	if err := s.add(ListenerHTTPS, network, addr, httpsServer, true); err != nil {
+		return nil, err
+	}
+
+	if serverSetting.TLS.RedirectHTTP {
+		redirectServer := newHTTPServer(RedirectHandler(serverSetting.TLS.HttpsPort))
+		if err := s.add(ListenerRedirect, "tcp", ":"+serverSetting.HttpPort, redirectServer, false); err != nil {
+			s.closeListeners()
+			return nil, err
+		}
+	}
+	s.stopWatch = reloader.Watch(serverSetting.TLS.ReloadInterval)
+	return s, nil
+}
+
+// mainAddr 配置了 UnixSocket 时主服务监听 unix socket, 否则监听 tcpAddr
+func mainAddr(tcpAddr string) (network, addr string) {
+	if setting.ServerSetting.UnixSocket != "" {
+		return "unix", setting.ServerSetting.UnixSocket
+	}
+	return "tcp", tcpAddr
+}
+
+func newHTTPServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:        handler,
+		ReadTimeout:    setting.ServerSetting.ReadTimeout,
+		WriteTimeout:   setting.ServerSetting.WriteTimeout,
+		MaxHeaderBytes: 1 << 20,
+		// TLS 握手失败等错误输出到应用日志
+		ErrorLog: zap.NewStdLog(logging.Logger),
+	}
+}
+
+func (s *Server) add(name, network, addr string, srv *http.Server, tls bool) error {
+	ln, err := listen(name, network, addr, unixSocketMode())
+	if err != nil {
+		return errors.Wrapf(err, "listen %s %s", network, addr)
+	}
+	srv.Addr = ln.Addr().String()
+	s.entries = append(s.entries, &entry{name: name, srv: srv, listener: ln, tls: tls})
+	return nil
+}
+
+func unixSocketMode() os.FileMode {
+	mode, err := strconv.ParseUint(setting.ServerSetting.UnixSocketMode, 8, 32)
+	if err != nil {
+		return 0
+	}
+	return os.FileMode(mode)
+}
+
+func (s *Server) closeListeners() {
+	for _, e := range s.entries {
+		_ = e.listener.Close()
+	}
+}
+
+// Start 在后台启动全部监听, 任一监听启动失败时退出进程.
+// 由 Restart 启动的进程在启动监听后通知父进程退出.
+func (s *Server) Start() {
+	for _, e := range s.entries {
+		go func(e *entry) {
+			logging.Logger.Info("web server starting ...",
+				zap.String("listener", e.name), zap.String("addr", e.srv.Addr), zap.Bool("tls", e.tls))
+
+			var err error
+			if e.tls {
+				// 证书由 TLSConfig.GetCertificate 提供
+				err = e.srv.ServeTLS(e.listener, "", "")
+			} else {
+				err = e.srv.Serve(e.listener)
+			}
+			if err != nil && err != http.ErrServerClosed {
+				logging.Logger.Fatal("web Server start Failed", zap.String("addr", e.srv.Addr), zap.Error(err))
+			}
+		}(e)
+	}
+
+	if err := notifyReady(); err != nil {
+		logging.Logger.Error("notify parent process failed", zap.Error(err))
+	}
+}
+
+// Restart 以相同的参数重新执行当前程序, 通过 LISTEN_FDS 将监听交给新进程.
+// 新进程的监听开始接收请求后通过继承的管道通知当前进程, RestartTimeout 内没有收到通知时
+// 结束新进程并返回错误, 当前进程继续提供服务; 成功后当前进程需要调用 Shutdown 处理完已接收的请求后退出.
+func (s *Server) Restart() (int, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+
+	files := make([]*os.File, 0, len(s.entries))
+	names := make([]string, 0, len(s.entries))
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	for _, e := range s.entries {
+		f, err := listenerFile(e.listener)
+		if err != nil {
+			return 0, errors.Wrapf(err, "dup listener %s", e.name)
+		}
+		files = append(files, f)
+		names = append(names, e.name)
+	}
+
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return 0, errors.Wrap(err, "create ready pipe")
+	}
+	defer readyReader.Close()
+	// 写端在监听之后传给新进程, 不计入 LISTEN_FDS
+	readyFd := listenFdsStart + len(files)
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(files, readyWriter)
+	cmd.Env = append(os.Environ(),
+		envListenFds+"="+strconv.Itoa(len(files)),
+		envListenFdNames+"="+strings.Join(names, ":"),
+		envReadyFd+"="+strconv.Itoa(readyFd),
+	)
+	err = cmd.Start()
+	// 关闭当前进程持有的写端, 新进程退出时读端返回 EOF
+	_ = readyWriter.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	pid := cmd.Process.Pid
+	if err := waitReady(readyReader, setting.ServerSetting.RestartTimeout); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return 0, errors.Wrapf(err, "new process %d is not ready", pid)
+	}
+	// 不等待子进程, 当前进程退出后由 init 接管
+	_ = cmd.Process.Release()
+	return pid, nil
+}
+
+// Shutdown 并行关闭全部监听, 等待处理中的请求完成
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopWatch()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for _, e := range s.entries {
+		wg.Add(1)
+		go func(srv *http.Server) {
+			defer wg.Done()
+			if err := srv.Shutdown(ctx); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(e.srv)
+	}
+	wg.Wait()
+	return firstErr
+}
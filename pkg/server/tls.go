@@ -0,0 +1,175 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"gin-example/pkg/logging"
+	"gin-example/pkg/setting"
+)
+
+// intermediateCipherSuites TLS 1.2 只允许前向安全的 ECDHE + AEAD 套件, 同时满足 HTTP/2 的要求.
+// TLS 1.3 的套件不可配置, 不受影响.
+var intermediateCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+}
+
+// NewTLSConfig 按配置生成 tls.Config, 服务端证书由 reloader 提供, 证书文件更新后无需重启
+func NewTLSConfig(tlsSetting setting.TLS, reloader *CertReloader) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		CipherSuites:   intermediateCipherSuites,
+		GetCertificate: reloader.GetCertificate,
+		// 开启 HTTP/2
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	if tlsSetting.MinVersion == "1.3" || tlsSetting.CipherPolicy == "modern" {
+		config.MinVersion = tls.VersionTLS13
+	}
+
+	switch tlsSetting.ClientAuth {
+	case "request":
+		config.ClientAuth = tls.RequestClientCert
+	case "verify-if-given":
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		config.ClientAuth = tls.NoClientCert
+	}
+
+	if tlsSetting.ClientCAFile != "" {
+		pool, err := loadCertPool(tlsSetting.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+	}
+	return config, nil
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "read client CA file")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.Errorf("no certificate found in %s", caFile)
+	}
+	return pool, nil
+}
+
+// CertReloader 持有当前的服务端证书, 证书或私钥文件的修改时间变化后重新加载
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// NewCertReloader 加载证书, 证书或私钥无法加载时返回错误
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload 从磁盘重新加载证书, 加载失败时继续使用原证书
+func (r *CertReloader) Reload() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "load certificate")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Watch 每隔 interval 检查证书文件, 修改后重新加载, 返回停止检查的函数
+func (r *CertReloader) Watch(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.reloadIfChanged()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { once.Do(func() { close(done) }) }
+}
+
+func (r *CertReloader) reloadIfChanged() {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		logging.Logger.Error("stat certificate failed", zap.Error(err))
+		return
+	}
+	r.mu.RLock()
+	changed := modTime.After(r.modTime)
+	r.mu.RUnlock()
+	if !changed {
+		return
+	}
+
+	if err := r.Reload(); err != nil {
+		logging.Logger.Error("certificate reload rejected, keep the old one", zap.Error(err))
+		return
+	}
+	logging.Logger.Info("certificate reloaded.", zap.String("certFile", r.certFile))
+}
+
+// latestModTime 证书和私钥中较晚的修改时间
+func (r *CertReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, errors.Wrap(err, "stat certificate")
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
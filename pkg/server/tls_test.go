@@ -0,0 +1,262 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gin-example/pkg/logging"
+	"gin-example/pkg/setting"
+)
+
+// testCA 测试用的自签名 CA
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gin-example test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue 签发证书, 返回 PEM 格式的证书和私钥
+func (ca *testCA) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFile 写入文件并把修改时间设为 modTime, 避免文件系统时间精度导致修改不可见
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serveTLS 在随机端口上完成握手后写入 "ok", 返回监听地址
+func serveTLS(t *testing.T, config *tls.Config) string {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if err := conn.(*tls.Conn).Handshake(); err != nil {
+					return
+				}
+				_, _ = conn.Write([]byte("ok"))
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// dial 握手并读取服务端的响应, TLS 1.3 下服务端拒绝客户端证书的错误在第一次读取时返回
+func dial(addr string, config *tls.Config) (*x509.Certificate, error) {
+	conn, err := tls.Dial("tcp", addr, config)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	buf := make([]byte, 2)
+	if _, err := conn.Read(buf); err != nil {
+		return nil, err
+	}
+	return conn.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestCertReloaderReload(t *testing.T) {
+	logging.Logger = zap.NewNop()
+
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+
+	modTime := time.Now().Add(-time.Minute)
+	certPEM, keyPEM := ca.issue(t, 100, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM, modTime)
+	writeFile(t, keyFile, keyPEM, modTime)
+
+	reloader, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := NewTLSConfig(setting.TLS{}, reloader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := serveTLS(t, config)
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.pem)
+	clientConfig := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+
+	cert, err := dial(addr, clientConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.SerialNumber.Int64() != 100 {
+		t.Fatalf("serial = %d, want 100", cert.SerialNumber.Int64())
+	}
+
+	// 无效的证书文件被拒绝, 继续使用原证书
+	modTime = modTime.Add(time.Second)
+	writeFile(t, certFile, []byte("not a certificate"), modTime)
+	if err := reloader.Reload(); err == nil {
+		t.Fatal("reload invalid certificate: want error, got nil")
+	}
+	if cert, err = dial(addr, clientConfig); err != nil {
+		t.Fatal(err)
+	}
+	if cert.SerialNumber.Int64() != 100 {
+		t.Fatalf("serial after rejected reload = %d, want 100", cert.SerialNumber.Int64())
+	}
+
+	// 替换证书后由 Watch 重新加载, 已建立的监听无需重启
+	stop := reloader.Watch(10 * time.Millisecond)
+	defer stop()
+	modTime = modTime.Add(time.Second)
+	certPEM, keyPEM = ca.issue(t, 200, x509.ExtKeyUsageServerAuth)
+	writeFile(t, keyFile, keyPEM, modTime)
+	writeFile(t, certFile, certPEM, modTime)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if cert, err = dial(addr, clientConfig); err != nil {
+			t.Fatal(err)
+		}
+		if cert.SerialNumber.Int64() == 200 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("serial = %d, want 200 after the certificate is replaced", cert.SerialNumber.Int64())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	logging.Logger = zap.NewNop()
+
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	caFile := filepath.Join(dir, "ca.crt")
+
+	now := time.Now()
+	certPEM, keyPEM := ca.issue(t, 100, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM, now)
+	writeFile(t, keyFile, keyPEM, now)
+	writeFile(t, caFile, ca.pem, now)
+
+	reloader, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := NewTLSConfig(setting.TLS{ClientCAFile: caFile, ClientAuth: "require"}, reloader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := serveTLS(t, config)
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.pem)
+
+	// 没有客户端证书
+	if _, err := dial(addr, &tls.Config{RootCAs: roots, ServerName: "localhost"}); err == nil {
+		t.Fatal("dial without client certificate: want error, got nil")
+	}
+
+	// 其他 CA 签发的客户端证书
+	otherPEM, otherKeyPEM := newTestCA(t).issue(t, 300, x509.ExtKeyUsageClientAuth)
+	other, err := tls.X509KeyPair(otherPEM, otherKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dial(addr, &tls.Config{RootCAs: roots, ServerName: "localhost", Certificates: []tls.Certificate{other}}); err == nil {
+		t.Fatal("dial with untrusted client certificate: want error, got nil")
+	}
+
+	// ClientCAFile 签发的客户端证书
+	clientPEM, clientKeyPEM := ca.issue(t, 400, x509.ExtKeyUsageClientAuth)
+	client, err := tls.X509KeyPair(clientPEM, clientKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dial(addr, &tls.Config{RootCAs: roots, ServerName: "localhost", Certificates: []tls.Certificate{client}}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,34 @@
+//go:build !windows
+// +build !windows
+
+package sessions
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile 对整个文件加 flock, 阻塞直到获得锁; 多个进程共享同一目录时同样有效
+func lockFile(f *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	for {
+		err := syscall.Flock(int(f.Fd()), how)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
+
+// removeLocked 持有锁时删除文件, 然后释放锁并关闭
+func removeLocked(f *os.File) error {
+	err := os.Remove(f.Name())
+	closeLocked(f)
+	return err
+}
@@ -0,0 +1,90 @@
+//go:build !windows
+// +build !windows
+
+package sessions
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type openResult struct {
+	f   *os.File
+	err error
+}
+
+// lockAndWait 持有 filename 的排他锁, 在另一个 goroutine 中等待 openLocked 获得锁
+func lockAndWait(t *testing.T, filename string) (*os.File, <-chan openResult) {
+	t.Helper()
+	held, err := openLocked(filename, os.O_RDONLY, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make(chan openResult, 1)
+	go func() {
+		f, err := openLocked(filename, os.O_RDONLY, false)
+		result <- openResult{f, err}
+	}()
+
+	// 确认在等待锁
+	select {
+	case r := <-result:
+		t.Fatalf("openLocked returns %v while the file is locked", r.err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	return held, result
+}
+
+// 等待锁期间文件被替换时重新打开新文件
+func TestOpenLockedReplaced(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "session_replaced")
+	if err := ioutil.WriteFile(filename, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	held, result := lockAndWait(t, filename)
+
+	tmp := filepath.Join(dir, "tmp")
+	if err := ioutil.WriteFile(tmp, []byte("new"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		t.Fatal(err)
+	}
+	closeLocked(held)
+
+	r := <-result
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	defer closeLocked(r.f)
+	data, err := ioutil.ReadAll(r.f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new" {
+		t.Fatalf("openLocked read %q, want the replaced file", data)
+	}
+}
+
+// 只读打开时等待锁期间文件被删除, 返回不存在
+func TestOpenLockedRemoved(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "session_removed")
+	if err := ioutil.WriteFile(filename, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	held, result := lockAndWait(t, filename)
+
+	if err := removeLocked(held); err != nil {
+		t.Fatal(err)
+	}
+	if r := <-result; !os.IsNotExist(r.err) {
+		if r.f != nil {
+			closeLocked(r.f)
+		}
+		t.Fatalf("openLocked of removed file: want not exist error, got %v", r.err)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build windows
+// +build windows
+
+package sessions
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile 使用 LockFileEx 锁定整个文件, 阻塞直到获得锁
+func lockFile(f *os.File, exclusive bool) error {
+	var flags uint32
+	if exclusive {
+		flags = windows.LOCKFILE_EXCLUSIVE_LOCK
+	}
+	return windows.LockFileEx(windows.Handle(f.Fd()), flags, 0, ^uint32(0), ^uint32(0), new(windows.Overlapped))
+}
+
+func unlockFile(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, ^uint32(0), ^uint32(0), new(windows.Overlapped))
+}
+
+// removeLocked windows 不能删除已打开的文件, 先释放锁并关闭再删除
+func removeLocked(f *os.File) error {
+	closeLocked(f)
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,70 @@
+package sessions
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Cleanup 删除修改时间早于 Options.MaxAge 的 session 文件, MaxAge <= 0 时不删除.
+// 每次 Save 都会更新修改时间, 因此只删除超过 MaxAge 没有保存过的 session.
+func (s *FilesystemStore) Cleanup() (int, error) {
+	if s.Options.MaxAge <= 0 {
+		return 0, nil
+	}
+	maxAge := time.Duration(s.Options.MaxAge) * time.Second
+
+	infos, err := ioutil.ReadDir(s.path)
+	if err != nil {
+		return 0, err
+	}
+	var removed int
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasPrefix(info.Name(), "session_") || time.Since(info.ModTime()) < maxAge {
+			continue
+		}
+		ok, err := removeExpired(filepath.Join(s.path, info.Name()), maxAge)
+		if err != nil {
+			return removed, err
+		}
+		if ok {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// removeExpired 加锁后重新检查修改时间, 等待锁期间 session 被保存时不删除
+func removeExpired(filename string, maxAge time.Duration) (bool, error) {
+	f, err := openLocked(filename, os.O_RDONLY, true)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		closeLocked(f)
+		return false, err
+	}
+	if time.Since(info.ModTime()) < maxAge {
+		closeLocked(f)
+		return false, nil
+	}
+	if err := removeLocked(f); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	return true, nil
+}
+
+// StartCleanup 每隔 interval 执行一次 Cleanup, 调用返回的函数停止
+func (s *FilesystemStore) StartCleanup(interval time.Duration, onError func(err error)) (stop func()) {
+	return startCleanup(interval, func() {
+		if _, err := s.Cleanup(); err != nil && onError != nil {
+			onError(err)
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package sessions
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFilesystemStore(t *testing.T) {
+	store := NewFilesystemStore(t.TempDir(), testHashKey)
+
+	session, cookie := saveNew(t, store, map[interface{}]interface{}{"userId": uint(1)})
+	loaded, r := load(t, store, cookie)
+	if loaded.IsNew || loaded.Values["userId"] != uint(1) {
+		t.Fatalf("loaded session = %+v, want userId 1", loaded)
+	}
+
+	loaded.Options.MaxAge = -1
+	save(t, store, r, loaded)
+	if _, err := os.Stat(filepath.Join(store.path, "session_"+session.ID)); !os.IsNotExist(err) {
+		t.Fatalf("session file is not deleted: %v", err)
+	}
+}
+
+// Cleanup 只删除超过 MaxAge 未保存的 session 文件
+func TestFilesystemStoreCleanup(t *testing.T) {
+	cases := []struct {
+		name    string
+		age     time.Duration
+		dir     bool
+		removed bool
+	}{
+		{name: "session_expired", age: 2 * time.Minute, removed: true},
+		{name: "session_fresh", age: 30 * time.Second},
+		{name: "other_expired", age: 2 * time.Minute},
+		{name: "session_dir", age: 2 * time.Minute, dir: true},
+	}
+
+	dir := t.TempDir()
+	for _, c := range cases {
+		path := filepath.Join(dir, c.name)
+		var err error
+		if c.dir {
+			err = os.Mkdir(path, 0700)
+		} else {
+			err = ioutil.WriteFile(path, []byte("data"), 0600)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		modTime := time.Now().Add(-c.age)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := NewFilesystemStore(dir, testHashKey)
+	store.MaxAge(0)
+	if removed, err := store.Cleanup(); err != nil || removed != 0 {
+		t.Fatalf("Cleanup with MaxAge 0 = %d, %v, want nothing removed", removed, err)
+	}
+
+	store.MaxAge(60)
+	removed, err := store.Cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Fatalf("Cleanup removed %d files, want 1", removed)
+	}
+	for _, c := range cases {
+		_, err := os.Stat(filepath.Join(dir, c.name))
+		if exist := err == nil; exist == c.removed {
+			t.Errorf("%s exists = %v after Cleanup", c.name, exist)
+		}
+	}
+}
+
+// 列出目录后 session 被保存时, 加锁后按新的修改时间判断, 不删除
+func TestRemoveExpiredRechecksModTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session_saved")
+	if err := ioutil.WriteFile(path, []byte("data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := removeExpired(path, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed {
+		t.Fatal("recently saved session file is removed")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// 已被其他进程删除
+	if removed, err := removeExpired(path+"_missing", time.Minute); err != nil || removed {
+		t.Fatalf("removeExpired of missing file = %v, %v, want false, nil", removed, err)
+	}
+}
+
+func TestFilesystemStoreStartCleanup(t *testing.T) {
+	store := NewFilesystemStore(t.TempDir(), testHashKey)
+	store.MaxAge(60)
+	session, _ := saveNew(t, store, nil)
+	path := filepath.Join(store.path, "session_"+session.ID)
+	modTime := time.Now().Add(-2 * time.Minute)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	// 停止后正在执行的清理仍可能回调, 不能在回调中使用 t
+	errs := make(chan error, 1)
+	stop := store.StartCleanup(5*time.Millisecond, func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+	defer stop()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expired session file is not removed by StartCleanup")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	select {
+	case err := <-errs:
+		t.Fatal(err)
+	default:
+	}
+}
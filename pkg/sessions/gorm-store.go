@@ -0,0 +1,243 @@
+package sessions
+
+import (
+	"context"
+	"encoding/base32"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"gin-example/pkg/database"
+	"gin-example/pkg/secure-cookie"
+)
+
+const defaultSessionTable = "session"
+
+// sessionRecord 数据库中的一条 session, 表结构见 models/migrations
+type sessionRecord struct {
+	ID        string `gorm:"primaryKey"`
+	Data      []byte
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// GormStore 通过 GORM 把 session 保存在数据库中, cookie 中只保存 session ID.
+// 读取时使用主库, 避免登录后立即请求时副本还没有同步; 过期的记录由 StartCleanup 定时删除.
+type GormStore struct {
+	// need by secure cookie
+	Codecs  []securecookie.Codec
+	Options *Options
+
+	defaultMaxAge    int // default TTL for session, 0 No limit
+	defaultMaxLength int // default Max session size, 0 No limit
+
+	db         *gorm.DB
+	table      string
+	serializer SessionSerializerInterface
+}
+
+// NewGormStore returns a new GormStore.
+func NewGormStore(db *gorm.DB, keyPairs ...[]byte) *GormStore {
+	return &GormStore{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &Options{
+			Path:   "/",
+			MaxAge: defaultSessionExpire,
+		},
+		defaultMaxAge:    defaultSessionExpire,
+		defaultMaxLength: defaultSessionMaxLength,
+		db:               db,
+		table:            defaultSessionTable,
+		serializer:       GobSerializer{},
+	}
+}
+
+// SetTable 设置 session 表名, 需要包含表前缀
+func (s *GormStore) SetTable(table string) {
+	s.table = table
+}
+
+// SetMaxLength 限制单个 session 序列化后的长度, 0 不限制
+func (s *GormStore) SetMaxLength(maxLength int) {
+	if maxLength >= 0 {
+		s.defaultMaxLength = maxLength
+	}
+}
+
+// SetSerializer sets the serializer
+func (s *GormStore) SetSerializer(i SessionSerializerInterface) {
+	s.serializer = i
+}
+
+// SetMaxAge session 的过期时间, 单位秒, 0 不过期
+func (s *GormStore) SetMaxAge(maxAge int) {
+	s.defaultMaxAge = maxAge
+}
+
+// Get returns a session for the given name after adding it to the registry.
+func (s *GormStore) Get(r *http.Request, name string) (*Session, error) {
+	return GetRegistry(r).Get(s, name)
+}
+
+// New returns a session for the given name without adding it to the registry.
+func (s *GormStore) New(r *http.Request, name string) (*Session, error) {
+	var (
+		err error
+		ok  bool
+	)
+	session := NewSession(s, name)
+	ops := *s.Options // make a copy
+	ops.MaxAge = s.defaultMaxAge
+	session.Options = &ops
+	session.IsNew = true
+	if c, errCookie := r.Cookie(name); errCookie == nil {
+		err = securecookie.DecodeMulti(name, c.Value, &session.ID, s.Codecs...)
+		if err == nil {
+			ok, err = s.load(r.Context(), session)
+			session.IsNew = !(err == nil && ok) // not new if no error and data available
+		}
+	}
+	return session, err
+}
+
+// Save adds a single session to the response.
+func (s *GormStore) Save(r *http.Request, w http.ResponseWriter, session *Session) error {
+	// Marked for deletion.
+	if session.Options.MaxAge <= 0 {
+		if err := s.delete(r.Context(), session.ID); err != nil {
+			return err
+		}
+		http.SetCookie(w, NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = strings.TrimRight(base32.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(32)), "=")
+	}
+	if err := s.save(r.Context(), session); err != nil {
+		return err
+	}
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// load returns true if there is a session data in database
+func (s *GormStore) load(ctx context.Context, session *Session) (bool, error) {
+	var record sessionRecord
+	err := s.db.WithContext(database.WithPrimary(ctx)).Table(s.table).
+		Where("id = ? AND expires_at > ?", session.ID, time.Now()).
+		First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, s.serializer.Deserialize(record.Data, session)
+}
+
+// save 不存在时新增, 存在时更新数据及过期时间
+func (s *GormStore) save(ctx context.Context, session *Session) error {
+	b, err := s.serializer.Serialize(session)
+	if err != nil {
+		return err
+	}
+	if s.defaultMaxLength != 0 && len(b) > s.defaultMaxLength {
+		return errors.New("SessionStore: the value to store is too big")
+	}
+
+	age := session.Options.MaxAge
+	if age == 0 {
+		age = s.defaultMaxAge
+	}
+	// 不过期的 session 使用足够远的过期时间, 读取时统一按 expires_at 判断
+	expiresAt := time.Now().AddDate(100, 0, 0)
+	if age > 0 {
+		expiresAt = time.Now().Add(time.Duration(age) * time.Second)
+	}
+
+	record := sessionRecord{ID: session.ID, Data: b, ExpiresAt: expiresAt}
+	return s.db.WithContext(ctx).Table(s.table).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"data", "expires_at", "updated_at"}),
+	}).Create(&record).Error
+}
+
+func (s *GormStore) delete(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Table(s.table).Where("id = ?", id).Delete(&sessionRecord{}).Error
+}
+
+// Cleanup 删除全部过期的 session
+func (s *GormStore) Cleanup(ctx context.Context) (int64, error) {
+	db := s.db.WithContext(ctx).Table(s.table).Where("expires_at <= ?", time.Now()).Delete(&sessionRecord{})
+	return db.RowsAffected, db.Error
+}
+
+// StartCleanup 每隔 interval 执行一次 Cleanup, 调用返回的函数停止
+func (s *GormStore) StartCleanup(interval time.Duration, onError func(err error)) (stop func()) {
+	return startCleanup(interval, func() {
+		if _, err := s.Cleanup(context.Background()); err != nil && onError != nil {
+			onError(err)
+		}
+	})
+}
+
+// userSessionRecord session 表中的用户及设备信息
+type userSessionRecord struct {
+	ID         string
+	IP         string
+	UserAgent  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+}
+
+// TouchUserSession 更新 session 记录的用户及设备信息, session 不存在时不更新; 创建时间使用 session 记录的创建时间
+func (s *GormStore) TouchUserSession(ctx context.Context, userID uint, info SessionInfo) error {
+	return s.db.WithContext(ctx).Table(s.table).
+		Where("id = ?", info.ID).
+		Where("(user_id IS NULL OR user_id <> ? OR last_seen_at IS NULL OR last_seen_at <= ? OR ip <> ? OR user_agent <> ?)",
+			userID, info.LastSeenAt.Add(-touchInterval), info.IP, info.UserAgent).
+		Updates(map[string]interface{}{
+			"user_id":      userID,
+			"ip":           info.IP,
+			"user_agent":   info.UserAgent,
+			"last_seen_at": info.LastSeenAt,
+		}).Error
+}
+
+// UserSessions 用户全部未过期的 session, 使用主库避免刚登录的 session 不在列表中
+func (s *GormStore) UserSessions(ctx context.Context, userID uint) ([]SessionInfo, error) {
+	var records []userSessionRecord
+	err := s.db.WithContext(database.WithPrimary(ctx)).Table(s.table).
+		Select("id, ip, user_agent, created_at, last_seen_at").
+		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]SessionInfo, 0, len(records))
+	for _, record := range records {
+		infos = append(infos, SessionInfo(record))
+	}
+	return infos, nil
+}
+
+// RevokeUserSessions 删除 ids 中属于该用户的 session
+func (s *GormStore) RevokeUserSessions(ctx context.Context, userID uint, ids ...string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	db := s.db.WithContext(ctx).Table(s.table).Where("user_id = ? AND id IN ?", userID, ids).Delete(&sessionRecord{})
+	return int(db.RowsAffected), db.Error
+}
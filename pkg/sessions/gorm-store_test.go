@@ -0,0 +1,88 @@
+package sessions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gin-example/pkg/database"
+)
+
+func newTestGormStore(t *testing.T) *GormStore {
+	t.Helper()
+	store := NewGormStore(database.GetGormDB(), testHashKey)
+	store.SetTable("blog_session")
+	t.Cleanup(func() {
+		database.GetGormDB().Exec("DELETE FROM blog_session")
+	})
+	return store
+}
+
+func (s *GormStore) count(t *testing.T, where string, args ...interface{}) int64 {
+	t.Helper()
+	var n int64
+	if err := s.db.Table(s.table).Where(where, args...).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func (s *GormStore) expire(t *testing.T, id string) {
+	t.Helper()
+	if err := s.db.Table(s.table).Where("id = ?", id).Update("expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 同一个 session 再次保存时更新数据, 不新增记录
+func TestGormStoreUpsert(t *testing.T) {
+	store := newTestGormStore(t)
+
+	session, cookie := saveNew(t, store, map[interface{}]interface{}{"userId": uint(1)})
+	loaded, r := load(t, store, cookie)
+	if loaded.IsNew || loaded.Values["userId"] != uint(1) {
+		t.Fatalf("loaded session = %+v, want userId 1", loaded)
+	}
+
+	loaded.Values["userId"] = uint(2)
+	save(t, store, r, loaded)
+	if n := store.count(t, "id = ?", session.ID); n != 1 {
+		t.Fatalf("%d records of session %s, want 1", n, session.ID)
+	}
+	if loaded, _ = load(t, store, cookie); loaded.Values["userId"] != uint(2) {
+		t.Fatalf("userId after update = %v, want 2", loaded.Values["userId"])
+	}
+
+	loaded.Options.MaxAge = -1
+	save(t, store, r, loaded)
+	if n := store.count(t, "id = ?", session.ID); n != 0 {
+		t.Fatalf("session %s is not deleted", session.ID)
+	}
+}
+
+// 过期的 session 读取时不可见, Cleanup 只删除过期的记录
+func TestGormStoreExpire(t *testing.T) {
+	store := newTestGormStore(t)
+
+	expired, expiredCookie := saveNew(t, store, nil)
+	valid, validCookie := saveNew(t, store, nil)
+	store.expire(t, expired.ID)
+
+	if loaded, _ := load(t, store, expiredCookie); !loaded.IsNew {
+		t.Fatal("expired session is loaded")
+	}
+	if loaded, _ := load(t, store, validCookie); loaded.IsNew {
+		t.Fatal("valid session is not loaded")
+	}
+
+	removed, err := store.Cleanup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Fatalf("Cleanup removed %d sessions, want 1", removed)
+	}
+	if n := store.count(t, "id IN ?", []string{expired.ID, valid.ID}); n != 1 {
+		t.Fatalf("%d sessions left after Cleanup, want 1", n)
+	}
+}
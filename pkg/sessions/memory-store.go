@@ -0,0 +1,259 @@
+package sessions
+
+import (
+	"context"
+	"encoding/base32"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gin-example/pkg/secure-cookie"
+)
+
+type memorySession struct {
+	data     []byte
+	expireAt time.Time
+
+	// 登录后记录所属的用户及设备信息, 见 TouchUserSession
+	userID uint
+	info   SessionInfo
+}
+
+// MemoryStore 进程内保存 session, cookie 中只保存 session ID.
+// 适用于单实例部署及测试, 重启后 session 丢失; 过期的 session 在读取时或 StartCleanup 定时删除.
+type MemoryStore struct {
+	// need by secure cookie
+	Codecs  []securecookie.Codec
+	Options *Options
+
+	defaultMaxAge    int // default TTL for session, 0 No limit
+	defaultMaxLength int // default Max session size, 0 No limit
+
+	serializer SessionSerializerInterface
+
+	mu       sync.RWMutex
+	sessions map[string]memorySession
+}
+
+// NewMemoryStore returns a new MemoryStore.
+func NewMemoryStore(keyPairs ...[]byte) *MemoryStore {
+	return &MemoryStore{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &Options{
+			Path:   "/",
+			MaxAge: defaultSessionExpire,
+		},
+		defaultMaxAge:    defaultSessionExpire,
+		defaultMaxLength: defaultSessionMaxLength,
+		serializer:       GobSerializer{},
+		sessions:         make(map[string]memorySession),
+	}
+}
+
+// SetMaxLength 限制单个 session 序列化后的长度, 0 不限制
+func (s *MemoryStore) SetMaxLength(maxLength int) {
+	if maxLength >= 0 {
+		s.defaultMaxLength = maxLength
+	}
+}
+
+// SetSerializer sets the serializer
+func (s *MemoryStore) SetSerializer(i SessionSerializerInterface) {
+	s.serializer = i
+}
+
+// SetMaxAge session 的过期时间, 单位秒, 0 不过期
+func (s *MemoryStore) SetMaxAge(maxAge int) {
+	s.defaultMaxAge = maxAge
+}
+
+// Get returns a session for the given name after adding it to the registry.
+func (s *MemoryStore) Get(r *http.Request, name string) (*Session, error) {
+	return GetRegistry(r).Get(s, name)
+}
+
+// New returns a session for the given name without adding it to the registry.
+func (s *MemoryStore) New(r *http.Request, name string) (*Session, error) {
+	var (
+		err error
+		ok  bool
+	)
+	session := NewSession(s, name)
+	ops := *s.Options // make a copy
+	ops.MaxAge = s.defaultMaxAge
+	session.Options = &ops
+	session.IsNew = true
+	if c, errCookie := r.Cookie(name); errCookie == nil {
+		err = securecookie.DecodeMulti(name, c.Value, &session.ID, s.Codecs...)
+		if err == nil {
+			ok, err = s.load(session)
+			session.IsNew = !(err == nil && ok) // not new if no error and data available
+		}
+	}
+	return session, err
+}
+
+// Save adds a single session to the response.
+func (s *MemoryStore) Save(r *http.Request, w http.ResponseWriter, session *Session) error {
+	// Marked for deletion.
+	if session.Options.MaxAge <= 0 {
+		s.delete(session.ID)
+		http.SetCookie(w, NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = strings.TrimRight(base32.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(32)), "=")
+	}
+	if err := s.save(session); err != nil {
+		return err
+	}
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// load returns true if there is a session data in memory
+func (s *MemoryStore) load(session *Session) (bool, error) {
+	s.mu.RLock()
+	stored, ok := s.sessions[session.ID]
+	s.mu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+	if !stored.expireAt.IsZero() && time.Now().After(stored.expireAt) {
+		s.delete(session.ID)
+		return false, nil
+	}
+	return true, s.serializer.Deserialize(stored.data, session)
+}
+
+func (s *MemoryStore) save(session *Session) error {
+	b, err := s.serializer.Serialize(session)
+	if err != nil {
+		return err
+	}
+	if s.defaultMaxLength != 0 && len(b) > s.defaultMaxLength {
+		return errors.New("SessionStore: the value to store is too big")
+	}
+
+	age := session.Options.MaxAge
+	if age == 0 {
+		age = s.defaultMaxAge
+	}
+	var expireAt time.Time
+	if age > 0 {
+		expireAt = time.Now().Add(time.Duration(age) * time.Second)
+	}
+
+	s.mu.Lock()
+	stored := s.sessions[session.ID]
+	stored.data, stored.expireAt = b, expireAt
+	s.sessions[session.ID] = stored
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) delete(id string) {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+}
+
+// Cleanup 删除全部过期的 session, 返回删除的数量
+func (s *MemoryStore) Cleanup() int {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for id, stored := range s.sessions {
+		if !stored.expireAt.IsZero() && now.After(stored.expireAt) {
+			delete(s.sessions, id)
+			removed++
+		}
+	}
+	return removed
+}
+
+// StartCleanup 每隔 interval 执行一次 Cleanup, 调用返回的函数停止
+func (s *MemoryStore) StartCleanup(interval time.Duration) (stop func()) {
+	return startCleanup(interval, func() { s.Cleanup() })
+}
+
+// TouchUserSession session 不存在时忽略, 创建时间使用首次记录的时间
+func (s *MemoryStore) TouchUserSession(ctx context.Context, userID uint, info SessionInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.sessions[info.ID]
+	if !ok {
+		return nil
+	}
+	if stored.userID == userID {
+		if !needTouch(stored.info, info) {
+			return nil
+		}
+		info.CreatedAt = stored.info.CreatedAt
+	}
+	stored.userID, stored.info = userID, info
+	s.sessions[info.ID] = stored
+	return nil
+}
+
+// UserSessions 遍历全部 session, 只适用于 session 数量不大的单实例
+func (s *MemoryStore) UserSessions(ctx context.Context, userID uint) ([]SessionInfo, error) {
+	now := time.Now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var infos []SessionInfo
+	for _, stored := range s.sessions {
+		if stored.userID != userID || (!stored.expireAt.IsZero() && now.After(stored.expireAt)) {
+			continue
+		}
+		infos = append(infos, stored.info)
+	}
+	sortSessionInfos(infos)
+	return infos, nil
+}
+
+// RevokeUserSessions 删除 ids 中属于该用户的 session
+func (s *MemoryStore) RevokeUserSessions(ctx context.Context, userID uint, ids ...string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revoked := 0
+	for _, id := range ids {
+		if stored, ok := s.sessions[id]; ok && stored.userID == userID {
+			delete(s.sessions, id)
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+// startCleanup 定时执行 cleanup, 停止函数可以重复调用
+func startCleanup(interval time.Duration, cleanup func()) (stop func()) {
+	done := make(chan struct{})
+	var once sync.Once
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				cleanup()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { once.Do(func() { close(done) }) }
+}
@@ -0,0 +1,110 @@
+package sessions
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore(testHashKey)
+
+	session, cookie := saveNew(t, store, map[interface{}]interface{}{"userId": uint(1)})
+	loaded, r := load(t, store, cookie)
+	if loaded.IsNew || loaded.ID != session.ID || loaded.Values["userId"] != uint(1) {
+		t.Fatalf("loaded session = %+v, want %s with userId 1", loaded, session.ID)
+	}
+
+	// MaxAge < 0 时删除
+	loaded.Options.MaxAge = -1
+	save(t, store, r, loaded)
+	if loaded, _ = load(t, store, cookie); !loaded.IsNew {
+		t.Fatal("session is still loaded after deleted")
+	}
+}
+
+// 过期的 session 读取时不可见, Cleanup 只删除过期的 session
+func TestMemoryStoreExpire(t *testing.T) {
+	store := NewMemoryStore(testHashKey)
+
+	cases := []struct {
+		name     string
+		expireAt time.Time
+		expired  bool
+	}{
+		{name: "expired", expireAt: time.Now().Add(-time.Second), expired: true},
+		{name: "valid", expireAt: time.Now().Add(time.Hour)},
+		{name: "no expiry", expired: false},
+	}
+	ids := make(map[string]string)
+	for _, c := range cases {
+		session, _ := saveNew(t, store, nil)
+		store.mu.Lock()
+		stored := store.sessions[session.ID]
+		stored.expireAt = c.expireAt
+		store.sessions[session.ID] = stored
+		store.mu.Unlock()
+		ids[c.name] = session.ID
+	}
+
+	if removed := store.Cleanup(); removed != 1 {
+		t.Fatalf("Cleanup removed %d sessions, want 1", removed)
+	}
+	for _, c := range cases {
+		store.mu.RLock()
+		_, ok := store.sessions[ids[c.name]]
+		store.mu.RUnlock()
+		if ok == c.expired {
+			t.Errorf("%s: session exists = %v after Cleanup", c.name, ok)
+		}
+	}
+}
+
+// 读取已过期但未清理的 session 时删除
+func TestMemoryStoreExpireOnLoad(t *testing.T) {
+	store := NewMemoryStore(testHashKey)
+	store.SetMaxAge(1)
+
+	session, cookie := saveNew(t, store, nil)
+	store.mu.Lock()
+	stored := store.sessions[session.ID]
+	stored.expireAt = time.Now().Add(-time.Millisecond)
+	store.sessions[session.ID] = stored
+	store.mu.Unlock()
+
+	if loaded, _ := load(t, store, cookie); !loaded.IsNew {
+		t.Fatal("expired session is loaded")
+	}
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if _, ok := store.sessions[session.ID]; ok {
+		t.Fatal("expired session is not deleted on load")
+	}
+}
+
+func TestMemoryStoreStartCleanup(t *testing.T) {
+	store := NewMemoryStore(testHashKey)
+	session, _ := saveNew(t, store, nil)
+	store.mu.Lock()
+	stored := store.sessions[session.ID]
+	stored.expireAt = time.Now().Add(-time.Second)
+	store.sessions[session.ID] = stored
+	store.mu.Unlock()
+
+	stop := store.StartCleanup(5 * time.Millisecond)
+	defer stop()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		store.mu.RLock()
+		n := len(store.sessions)
+		store.mu.RUnlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expired session is not removed by StartCleanup")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	// 停止函数可以重复调用
+	stop()
+}
@@ -11,9 +11,11 @@ import (
 	"encoding/gob"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/pkg/errors"
 
 	"gin-example/pkg/cache"
@@ -25,6 +27,7 @@ const (
 	defaultSessionExpire    = 86400 * 1
 	defaultSessionMaxLength = 4096
 	defaultRedisKeyPrefix   = "session:"
+	defaultUserKeyPrefix    = "session-user:"
 )
 
 // SessionSerializerInterface provides an interface hook for alternative serializers
@@ -83,6 +86,8 @@ func (s GobSerializer) Deserialize(d []byte, ss *Session) error {
 }
 
 // RedisStore stores sessions in a redis backend.
+// 用户的 session 索引保存在 <userKeyPrefix><userID> hash 中, field 为 session ID, 值为 JSON 编码的 SessionInfo.
+// userKeyPrefix 与 redisKeyPrefix 不同, 按 session 前缀扫描时不会包含用户索引.
 type RedisStore struct {
 	RedisClient cache.SessionCacheRedisClientInterface
 
@@ -94,6 +99,7 @@ type RedisStore struct {
 	defaultMaxLength int // default Max redis key size, 0 No limit
 
 	redisKeyPrefix string
+	userKeyPrefix  string
 
 	serializer SessionSerializerInterface
 }
@@ -110,6 +116,7 @@ func NewRedisStore(redisClientInterface cache.SessionCacheRedisClientInterface,
 		defaultMaxAge:    defaultSessionExpire, // 20 minutes seems like a reasonable default
 		defaultMaxLength: defaultSessionMaxLength,
 		redisKeyPrefix:   defaultRedisKeyPrefix,
+		userKeyPrefix:    defaultUserKeyPrefix,
 		serializer:       GobSerializer{},
 	}
 
@@ -133,6 +140,11 @@ func (s *RedisStore) SetRedisKeyPrefix(prefix string) {
 	s.redisKeyPrefix = prefix
 }
 
+// SetUserKeyPrefix 用户 session 索引的 key 前缀, 不能与 SetRedisKeyPrefix 的前缀相同
+func (s *RedisStore) SetUserKeyPrefix(prefix string) {
+	s.userKeyPrefix = prefix
+}
+
 // SetSerializer sets the serializer
 func (s *RedisStore) SetSerializer(i SessionSerializerInterface) {
 	s.serializer = i
@@ -192,6 +204,9 @@ func (s *RedisStore) New(r *http.Request, name string) (*Session, error) {
 // returns true if there is a session data in DB
 func (s *RedisStore) load(session *Session) (bool, error) {
 	data, err := s.RedisClient.Get(context.Background(), s.redisKeyPrefix+session.ID).Bytes()
+	if err == redis.Nil {
+		return false, nil // session 已过期或已删除
+	}
 	if err != nil {
 		return false, err
 	}
@@ -242,9 +257,7 @@ func (s *RedisStore) save(session *Session) error {
 	if age == 0 {
 		age = s.defaultMaxAge
 	}
-	err = s.RedisClient.Set(context.Background(), s.redisKeyPrefix+session.ID, b, time.Duration(age)*time.Second).Err()
-
-	return nil
+	return s.RedisClient.Set(context.Background(), s.redisKeyPrefix+session.ID, b, time.Duration(age)*time.Second).Err()
 }
 
 // Delete removes the session from redis, and sets the cookie to expire.
@@ -272,3 +285,91 @@ func (s *RedisStore) delete(session *Session) error {
 	}
 	return nil
 }
+
+func (s *RedisStore) userKey(userID uint) string {
+	return s.userKeyPrefix + strconv.FormatUint(uint64(userID), 10)
+}
+
+// TouchUserSession 写入用户的 session 索引, 索引的过期时间与新 session 相同, 有效的 session 都在索引过期前创建
+func (s *RedisStore) TouchUserSession(ctx context.Context, userID uint, info SessionInfo) error {
+	key := s.userKey(userID)
+	data, err := s.RedisClient.HGet(ctx, key, info.ID).Bytes()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	if err == nil {
+		var old SessionInfo
+		if json.Unmarshal(data, &old) == nil {
+			if !needTouch(old, info) {
+				return nil
+			}
+			info.CreatedAt = old.CreatedAt
+		}
+	}
+
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	if err := s.RedisClient.HSet(ctx, key, info.ID, b).Err(); err != nil {
+		return err
+	}
+	if s.defaultMaxAge > 0 {
+		return s.RedisClient.Expire(ctx, key, time.Duration(s.defaultMaxAge)*time.Second).Err()
+	}
+	return nil
+}
+
+// UserSessions 读取索引并检查 session 是否存在, 已过期的从索引中删除
+func (s *RedisStore) UserSessions(ctx context.Context, userID uint) ([]SessionInfo, error) {
+	key := s.userKey(userID)
+	all, err := s.RedisClient.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]SessionInfo, 0, len(all))
+	var stale []string
+	for id, data := range all {
+		n, err := s.RedisClient.Exists(ctx, s.redisKeyPrefix+id).Result()
+		if err != nil {
+			return nil, err
+		}
+		var info SessionInfo
+		if n == 0 || json.Unmarshal([]byte(data), &info) != nil {
+			stale = append(stale, id)
+			continue
+		}
+		infos = append(infos, info)
+	}
+	if len(stale) > 0 {
+		if err := s.RedisClient.HDel(ctx, key, stale...).Err(); err != nil {
+			return nil, err
+		}
+	}
+	sortSessionInfos(infos)
+	return infos, nil
+}
+
+// RevokeUserSessions 先删除 session 再删除索引, 删除失败时可以重试; cluster 模式下 key 不在同一个 slot, 逐个删除
+func (s *RedisStore) RevokeUserSessions(ctx context.Context, userID uint, ids ...string) (int, error) {
+	key := s.userKey(userID)
+	revoked := 0
+	for _, id := range ids {
+		err := s.RedisClient.HGet(ctx, key, id).Err()
+		if err == redis.Nil {
+			continue // 不属于该用户
+		}
+		if err != nil {
+			return revoked, err
+		}
+		if err := s.RedisClient.Del(ctx, s.redisKeyPrefix+id).Err(); err != nil {
+			return revoked, err
+		}
+		if err := s.RedisClient.HDel(ctx, key, id).Err(); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
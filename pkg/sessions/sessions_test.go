@@ -0,0 +1,96 @@
+package sessions
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gin-example/models"
+	"gin-example/pkg/database"
+	"gin-example/pkg/logging"
+	"gin-example/pkg/setting"
+)
+
+const testSessionName = "session"
+
+var testHashKey = []byte("0123456789abcdef0123456789abcdef")
+
+// TestMain 使用临时目录下的 sqlite 数据库文件, 执行全部 Migration 后运行测试, GormStore 使用其中的 session 表
+func TestMain(m *testing.M) {
+	os.Exit(runWithSQLite(m))
+}
+
+func runWithSQLite(m *testing.M) int {
+	dir, err := ioutil.TempDir("", "gin-example-sessions")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	logging.Logger = zap.NewNop()
+	logging.GormLogger = zap.NewNop()
+	setting.DatabaseSetting = &setting.Database{
+		DBType:      "sqlite",
+		TablePrefix: "blog_",
+		SQLite: setting.SQLiteOptions{
+			Path:        filepath.Join(dir, "test.db"),
+			BusyTimeout: 5 * time.Second,
+		},
+		MaxOpenConns: 1,
+		AutoMigrate:  true,
+	}
+	if err := database.Setup(); err != nil {
+		panic(err)
+	}
+	defer database.Close()
+	if err := models.Setup(); err != nil {
+		panic(err)
+	}
+	return m.Run()
+}
+
+// saveNew 创建并保存一个新的 session, 返回响应中的 cookie
+func saveNew(t *testing.T, store Store, values map[interface{}]interface{}) (*Session, *http.Cookie) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	session, err := store.New(r, testSessionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range values {
+		session.Values[k] = v
+	}
+	return session, save(t, store, r, session)
+}
+
+// save 保存 session, 返回响应中的 cookie
+func save(t *testing.T, store Store, r *http.Request, session *Session) *http.Cookie {
+	t.Helper()
+	w := httptest.NewRecorder()
+	if err := store.Save(r, w, session); err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Save set %d cookies, want 1", len(cookies))
+	}
+	return cookies[0]
+}
+
+// load 使用 cookie 读取 session, session 不存在时 IsNew 为 true
+func load(t *testing.T, store Store, cookie *http.Cookie) (*Session, *http.Request) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cookie)
+	session, err := store.New(r, testSessionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session, r
+}
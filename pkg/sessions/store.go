@@ -11,7 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"sync"
+	"time"
 
 	"gin-example/pkg/secure-cookie"
 )
@@ -126,8 +126,6 @@ func (s *CookieStore) MaxAge(age int) {
 
 // FilesystemStore ------------------------------------------------------------
 
-var fileMutex sync.RWMutex
-
 // NewFilesystemStore returns a new FilesystemStore.
 //
 // The path argument is the directory where sessions will be saved. If empty
@@ -156,6 +154,8 @@ func NewFilesystemStore(path string, keyPairs ...[]byte) *FilesystemStore {
 // It also serves as a reference for custom stores.
 //
 // This store is still experimental and not well tested. Feedback is welcome.
+//
+// 读写 session 文件时加文件锁, 同一 session 的并发请求不会写坏文件; 过期的文件由 StartCleanup 定时删除.
 type FilesystemStore struct {
 	Codecs  []securecookie.Codec
 	Options *Options // default configuration
@@ -259,17 +259,33 @@ func (s *FilesystemStore) save(session *Session) error {
 		return err
 	}
 	filename := filepath.Join(s.path, "session_"+session.ID)
-	fileMutex.Lock()
-	defer fileMutex.Unlock()
-	return ioutil.WriteFile(filename, []byte(encoded), 0600)
+	f, err := openLocked(filename, os.O_WRONLY|os.O_CREATE, true)
+	if err != nil {
+		return err
+	}
+	defer closeLocked(f)
+
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.Write([]byte(encoded)); err != nil {
+		return err
+	}
+	// 内容未变化时也更新修改时间, 清理过期文件按修改时间判断
+	now := time.Now()
+	return os.Chtimes(filename, now, now)
 }
 
 // load reads a file and decodes its content into session.Values.
 func (s *FilesystemStore) load(session *Session) error {
 	filename := filepath.Join(s.path, "session_"+session.ID)
-	fileMutex.RLock()
-	defer fileMutex.RUnlock()
-	fdata, err := ioutil.ReadFile(filename)
+	f, err := openLocked(filename, os.O_RDONLY, false)
+	if err != nil {
+		return err
+	}
+	defer closeLocked(f)
+
+	fdata, err := ioutil.ReadAll(f)
 	if err != nil {
 		return err
 	}
@@ -283,10 +299,46 @@ func (s *FilesystemStore) load(session *Session) error {
 // delete session file
 func (s *FilesystemStore) erase(session *Session) error {
 	filename := filepath.Join(s.path, "session_"+session.ID)
+	f, err := openLocked(filename, os.O_RDONLY, true)
+	if err != nil {
+		return err
+	}
+	return removeLocked(f)
+}
+
+// openLocked 打开文件并加锁. 等待锁期间文件可能被删除或替换, 加锁后确认仍是同一个文件, 否则重新打开
+func openLocked(filename string, flag int, exclusive bool) (*os.File, error) {
+	for {
+		f, err := os.OpenFile(filename, flag, 0600)
+		if err != nil {
+			return nil, err
+		}
+		if err := lockFile(f, exclusive); err != nil {
+			f.Close()
+			return nil, err
+		}
 
-	fileMutex.RLock()
-	defer fileMutex.RUnlock()
+		opened, err := f.Stat()
+		if err != nil {
+			closeLocked(f)
+			return nil, err
+		}
+		current, err := os.Stat(filename)
+		if err == nil && os.SameFile(opened, current) {
+			return f, nil
+		}
+		closeLocked(f)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		// 只读打开时文件已删除, 不再重试
+		if flag&os.O_CREATE == 0 && os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+}
 
-	err := os.Remove(filename)
-	return err
+func closeLocked(f *os.File) {
+	_ = unlockFile(f)
+	_ = f.Close()
 }
@@ -0,0 +1,41 @@
+package sessions
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// SessionInfo 用户 session 的设备信息, RedisStore 以 JSON 保存在用户的 session 索引中.
+// 旧版本写入的无 tag 字段名 (ID, IP ...) 按大小写不敏感匹配, 仍可以读取
+type SessionInfo struct {
+	ID         string    `json:"id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+}
+
+// UserSessionStore 可以按用户查看, 删除 session 的存储, 用于查看登录设备及强制下线
+type UserSessionStore interface {
+	// TouchUserSession 记录 session 所属的用户及设备信息, 已记录时更新最后访问时间, IP 及 User-Agent.
+	// 距上次更新不足 touchInterval 且设备信息未变化时不写入.
+	TouchUserSession(ctx context.Context, userID uint, info SessionInfo) error
+	// UserSessions 用户全部未过期的 session, 按最后访问时间倒序
+	UserSessions(ctx context.Context, userID uint) ([]SessionInfo, error)
+	// RevokeUserSessions 删除 ids 中属于该用户的 session, 返回删除的数量
+	RevokeUserSessions(ctx context.Context, userID uint, ids ...string) (int, error)
+}
+
+// 最后访问时间的更新间隔, 避免每个请求都写入存储
+const touchInterval = time.Minute
+
+func needTouch(old, info SessionInfo) bool {
+	return info.LastSeenAt.Sub(old.LastSeenAt) >= touchInterval || old.IP != info.IP || old.UserAgent != info.UserAgent
+}
+
+func sortSessionInfos(infos []SessionInfo) {
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].LastSeenAt.After(infos[j].LastSeenAt)
+	})
+}
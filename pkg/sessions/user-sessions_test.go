@@ -0,0 +1,369 @@
+package sessions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"gin-example/pkg/cache"
+)
+
+// fakeRedis 内存中的 SessionCacheRedisClientInterface, 只实现 session 使用的命令, 不处理过期时间
+type fakeRedis struct {
+	mu      sync.Mutex
+	strings map[string]string
+	hashes  map[string]map[string]string
+	hsets   int
+}
+
+var _ cache.SessionCacheRedisClientInterface = (*fakeRedis)(nil)
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{strings: make(map[string]string), hashes: make(map[string]map[string]string)}
+}
+
+func toString(value interface{}) string {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
+
+func (f *fakeRedis) Ping(ctx context.Context) *redis.StatusCmd {
+	return redis.NewStatusResult("PONG", nil)
+}
+
+func (f *fakeRedis) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.strings[key] = toString(value)
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (f *fakeRedis) Get(ctx context.Context, key string) *redis.StringCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	value, ok := f.strings[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(value, nil)
+}
+
+func (f *fakeRedis) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var n int64
+	for _, key := range keys {
+		if _, ok := f.strings[key]; ok {
+			delete(f.strings, key)
+			n++
+		}
+		if _, ok := f.hashes[key]; ok {
+			delete(f.hashes, key)
+			n++
+		}
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func (f *fakeRedis) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, isString := f.strings[key]
+	_, isHash := f.hashes[key]
+	return redis.NewBoolResult(isString || isHash, nil)
+}
+
+func (f *fakeRedis) TTL(ctx context.Context, key string) *redis.DurationCmd {
+	return redis.NewDurationResult(-1, nil)
+}
+
+func (f *fakeRedis) Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	prefix := strings.TrimSuffix(match, "*")
+	var keys []string
+	for key := range f.strings {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return redis.NewScanCmdResult(keys, 0, nil)
+}
+
+func (f *fakeRedis) Exists(ctx context.Context, keys ...string) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var n int64
+	for _, key := range keys {
+		_, isString := f.strings[key]
+		_, isHash := f.hashes[key]
+		if isString || isHash {
+			n++
+		}
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func (f *fakeRedis) HGet(ctx context.Context, key, field string) *redis.StringCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	value, ok := f.hashes[key][field]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(value, nil)
+}
+
+func (f *fakeRedis) HGetAll(ctx context.Context, key string) *redis.StringStringMapCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	all := make(map[string]string, len(f.hashes[key]))
+	for field, value := range f.hashes[key] {
+		all[field] = value
+	}
+	return redis.NewStringStringMapResult(all, nil)
+}
+
+// HSet 只支持 field, value 成对的参数
+func (f *fakeRedis) HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hsets++
+	hash, ok := f.hashes[key]
+	if !ok {
+		hash = make(map[string]string)
+		f.hashes[key] = hash
+	}
+	var n int64
+	for i := 0; i+1 < len(values); i += 2 {
+		field := toString(values[i])
+		if _, ok := hash[field]; !ok {
+			n++
+		}
+		hash[field] = toString(values[i+1])
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func (f *fakeRedis) HDel(ctx context.Context, key string, fields ...string) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var n int64
+	for _, field := range fields {
+		if _, ok := f.hashes[key][field]; ok {
+			delete(f.hashes[key], field)
+			n++
+		}
+	}
+	if len(f.hashes[key]) == 0 {
+		delete(f.hashes, key)
+	}
+	return redis.NewIntResult(n, nil)
+}
+
+func (f *fakeRedis) PoolStats() *redis.PoolStats {
+	return &redis.PoolStats{}
+}
+
+func (f *fakeRedis) Close() error {
+	return nil
+}
+
+type userSessionTestStore interface {
+	Store
+	UserSessionStore
+}
+
+func newTestRedisStore(t *testing.T) *RedisStore {
+	t.Helper()
+	store, err := NewRedisStore(newFakeRedis(), testHashKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+var userSessionStores = []struct {
+	name     string
+	newStore func(t *testing.T) userSessionTestStore
+}{
+	{"memory", func(t *testing.T) userSessionTestStore { return NewMemoryStore(testHashKey) }},
+	{"gorm", func(t *testing.T) userSessionTestStore { return newTestGormStore(t) }},
+	{"redis", func(t *testing.T) userSessionTestStore { return newTestRedisStore(t) }},
+}
+
+// 按用户列出 session, 只能删除属于该用户的 session
+func TestUserSessions(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+
+	for _, c := range userSessionStores {
+		t.Run(c.name, func(t *testing.T) {
+			store := c.newStore(t)
+
+			owners := []uint{1, 1, 2}
+			ids := make([]string, len(owners))
+			cookies := make([]string, len(owners))
+			for i, userID := range owners {
+				session, cookie := saveNew(t, store, nil)
+				ids[i], cookies[i] = session.ID, cookie.Value
+				info := SessionInfo{ID: session.ID, IP: "127.0.0.1", UserAgent: "test", CreatedAt: now, LastSeenAt: now.Add(time.Duration(i) * time.Second)}
+				if err := store.TouchUserSession(ctx, userID, info); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			infos, err := store.UserSessions(ctx, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 按最后访问时间倒序
+			if len(infos) != 2 || infos[0].ID != ids[1] || infos[1].ID != ids[0] {
+				t.Fatalf("UserSessions(1) = %+v, want %s, %s", infos, ids[1], ids[0])
+			}
+			if infos[0].IP != "127.0.0.1" || infos[0].UserAgent != "test" || !infos[0].LastSeenAt.Equal(now.Add(time.Second)) {
+				t.Fatalf("UserSessions(1)[0] = %+v", infos[0])
+			}
+
+			// 其他用户的 session 不会被删除
+			revoked, err := store.RevokeUserSessions(ctx, 1, ids[0], ids[2], "no-such-session")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if revoked != 1 {
+				t.Fatalf("RevokeUserSessions revoked %d sessions, want 1", revoked)
+			}
+			for i, want := range []int{1, 1} {
+				userID := uint(i + 1)
+				infos, err := store.UserSessions(ctx, userID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(infos) != want {
+					t.Errorf("UserSessions(%d) after revoke = %+v, want %d", userID, infos, want)
+				}
+			}
+		})
+	}
+}
+
+// 距上次更新不足 touchInterval 且设备信息未变化时不更新最后访问时间
+func TestTouchUserSessionThrottle(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+
+	cases := []struct {
+		name     string
+		info     SessionInfo
+		lastSeen time.Time
+	}{
+		{name: "within interval", info: SessionInfo{IP: "127.0.0.1", UserAgent: "test", LastSeenAt: now.Add(10 * time.Second)}, lastSeen: now},
+		{name: "interval elapsed", info: SessionInfo{IP: "127.0.0.1", UserAgent: "test", LastSeenAt: now.Add(touchInterval)}, lastSeen: now.Add(touchInterval)},
+		{name: "ip changed", info: SessionInfo{IP: "10.0.0.1", UserAgent: "test", LastSeenAt: now.Add(10 * time.Second)}, lastSeen: now.Add(10 * time.Second)},
+		{name: "user agent changed", info: SessionInfo{IP: "127.0.0.1", UserAgent: "other", LastSeenAt: now.Add(10 * time.Second)}, lastSeen: now.Add(10 * time.Second)},
+	}
+
+	for _, s := range userSessionStores {
+		for _, c := range cases {
+			t.Run(s.name+"/"+c.name, func(t *testing.T) {
+				store := s.newStore(t)
+				session, _ := saveNew(t, store, nil)
+				first := SessionInfo{ID: session.ID, IP: "127.0.0.1", UserAgent: "test", CreatedAt: now, LastSeenAt: now}
+				if err := store.TouchUserSession(ctx, 1, first); err != nil {
+					t.Fatal(err)
+				}
+
+				info := c.info
+				info.ID, info.CreatedAt = session.ID, info.LastSeenAt
+				if err := store.TouchUserSession(ctx, 1, info); err != nil {
+					t.Fatal(err)
+				}
+				infos, err := store.UserSessions(ctx, 1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(infos) != 1 {
+					t.Fatalf("UserSessions = %+v, want 1 session", infos)
+				}
+				if !infos[0].LastSeenAt.Equal(c.lastSeen) {
+					t.Fatalf("LastSeenAt = %v, want %v", infos[0].LastSeenAt, c.lastSeen)
+				}
+			})
+		}
+	}
+}
+
+// 索引中 session 已过期或数据无法解析的记录在读取时删除
+func TestRedisUserSessionsPruneStale(t *testing.T) {
+	ctx := context.Background()
+	store := newTestRedisStore(t)
+	client := store.RedisClient.(*fakeRedis)
+
+	live, _ := saveNew(t, store, nil)
+	expired, _ := saveNew(t, store, nil)
+	for _, session := range []*Session{live, expired} {
+		info := SessionInfo{ID: session.ID, CreatedAt: time.Now(), LastSeenAt: time.Now()}
+		if err := store.TouchUserSession(ctx, 1, info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// session 过期后索引中仍有记录
+	client.Del(ctx, defaultRedisKeyPrefix+expired.ID)
+	client.HSet(ctx, store.userKey(1), "corrupted", "not json")
+
+	infos, err := store.UserSessions(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].ID != live.ID {
+		t.Fatalf("UserSessions = %+v, want %s only", infos, live.ID)
+	}
+	all := client.HGetAll(ctx, store.userKey(1)).Val()
+	if len(all) != 1 {
+		t.Fatalf("user index after pruning = %v, want %s only", all, live.ID)
+	}
+	if _, ok := all[live.ID]; !ok {
+		t.Fatalf("live session %s is pruned from the user index", live.ID)
+	}
+}
+
+// 同一 session 的记录在设备信息变化时重写, 创建时间保持不变; 未变化时不写入
+func TestRedisTouchUserSessionKeepsCreatedAt(t *testing.T) {
+	ctx := context.Background()
+	store := newTestRedisStore(t)
+	client := store.RedisClient.(*fakeRedis)
+	now := time.Now().Truncate(time.Millisecond)
+
+	session, _ := saveNew(t, store, nil)
+	touch := func(info SessionInfo) {
+		t.Helper()
+		info.ID = session.ID
+		if err := store.TouchUserSession(ctx, 1, info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	touch(SessionInfo{IP: "127.0.0.1", CreatedAt: now, LastSeenAt: now})
+	touch(SessionInfo{IP: "127.0.0.1", CreatedAt: now.Add(time.Second), LastSeenAt: now.Add(time.Second)})
+	if client.hsets != 1 {
+		t.Fatalf("HSET called %d times, want 1", client.hsets)
+	}
+	touch(SessionInfo{IP: "10.0.0.1", CreatedAt: now.Add(2 * time.Second), LastSeenAt: now.Add(2 * time.Second)})
+	if client.hsets != 2 {
+		t.Fatalf("HSET called %d times, want 2", client.hsets)
+	}
+
+	infos, err := store.UserSessions(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || !infos[0].CreatedAt.Equal(now) || infos[0].IP != "10.0.0.1" {
+		t.Fatalf("UserSessions = %+v, want created at %v from 10.0.0.1", infos, now)
+	}
+}
@@ -0,0 +1,67 @@
+package setting
+
+import (
+	"io"
+	"reflect"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const secretMask = "******"
+
+// Current 返回当前生效的全部配置
+func Current() Config {
+	mu.RLock()
+	defer mu.RUnlock()
+	return Config{
+		Server:       *ServerSetting,
+		App:          *AppSetting,
+		JWT:          *JWTSetting,
+		Log:          *LoggerSetting,
+		Database:     *DatabaseSetting,
+		SessionRedis: *SessionRedisSetting,
+		Cache:        *CacheSetting,
+		Session:      *SessionSetting,
+	}
+}
+
+// Print 以 yaml 格式输出合并文件及环境变量后的最终配置, 标记 secret:"true" 的配置项以 ****** 代替
+func Print(w io.Writer) error {
+	out, err := yaml.Marshal(masked(reflect.ValueOf(Current())))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+func masked(v reflect.Value) interface{} {
+	switch {
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		return v.Interface().(time.Duration).String()
+	case v.Type() == reflect.TypeOf(Milliseconds(0)):
+		return v.Interface().(Milliseconds).Duration().String()
+	case v.Kind() == reflect.Struct:
+		m := yaml.MapSlice{}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			var value interface{}
+			if field.Tag.Get("secret") == "true" && !v.Field(i).IsZero() {
+				value = secretMask
+			} else {
+				value = masked(v.Field(i))
+			}
+			m = append(m, yaml.MapItem{Key: field.Name, Value: value})
+		}
+		return m
+	case v.Kind() == reflect.Slice:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = masked(v.Index(i))
+		}
+		return items
+	default:
+		return v.Interface()
+	}
+}
@@ -0,0 +1,103 @@
+package setting
+
+import (
+	"reflect"
+	"sync"
+)
+
+// 支持热加载的配置段, 其余配置段 (Server, Database, SessionRedis) 修改后需要重启生效
+const (
+	SectionApp = "App"
+	SectionJWT = "JWT"
+	SectionLog = "Log"
+)
+
+var (
+	// mu 保护可热加载的配置段, 运行期间读取这些配置段请使用 GetXxxSetting
+	mu sync.RWMutex
+
+	subscribersMu sync.Mutex
+	subscribers   = make(map[string][]func())
+
+	// reloadMu 串行执行 Reload, 每个配置文件的 watcher 在各自的 goroutine 中回调,
+	// 避免两次热加载交替替换配置及通知订阅者
+	reloadMu sync.Mutex
+)
+
+// GetAppSetting 返回当前 App 配置的副本
+func GetAppSetting() App {
+	mu.RLock()
+	defer mu.RUnlock()
+	return *AppSetting
+}
+
+// GetJWTSetting 返回当前 JWT 配置的副本
+func GetJWTSetting() JWT {
+	mu.RLock()
+	defer mu.RUnlock()
+	return *JWTSetting
+}
+
+// GetLoggerSetting 返回当前 Log 配置的副本
+func GetLoggerSetting() Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+	return *LoggerSetting
+}
+
+// Subscribe 订阅配置段变更, 热加载成功且该配置段发生变化后回调 fn
+func Subscribe(section string, fn func()) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	subscribers[section] = append(subscribers[section], fn)
+}
+
+// Reload 重新读取配置文件, 校验通过后替换可热加载的配置段并通知订阅者.
+// 校验失败时返回错误, 保留旧配置. 同一时间只执行一次, 订阅者按热加载的顺序收到通知.
+func Reload() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	s, err := New(configFile, profile)
+	if err != nil {
+		return err
+	}
+	config, err := s.readConfig()
+	if err != nil {
+		return err
+	}
+	if err := config.validate(); err != nil {
+		return err
+	}
+
+	changed := make([]string, 0, 3)
+	mu.Lock()
+	if !reflect.DeepEqual(*AppSetting, config.App) {
+		*AppSetting = config.App
+		changed = append(changed, SectionApp)
+	}
+	if !reflect.DeepEqual(*JWTSetting, config.JWT) {
+		*JWTSetting = config.JWT
+		changed = append(changed, SectionJWT)
+	}
+	if !reflect.DeepEqual(*LoggerSetting, config.Log) {
+		*LoggerSetting = config.Log
+		changed = append(changed, SectionLog)
+	}
+	mu.Unlock()
+
+	for _, section := range changed {
+		notify(section)
+	}
+	return nil
+}
+
+func notify(section string) {
+	subscribersMu.Lock()
+	fns := append([]func(){}, subscribers[section]...)
+	subscribersMu.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
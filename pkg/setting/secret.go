@@ -0,0 +1,103 @@
+package setting
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// SecretProvider 解析 secret 引用.
+// 标记 secret:"true" 的配置项可以写成 <scheme>:<ref> 的形式, 如 file:/run/secrets/db, env:DB_PASSWORD,
+// 加载配置时由 scheme 对应的 SecretProvider 解析为真实值, 未注册的 scheme 按字面值处理.
+type SecretProvider interface {
+	Resolve(ref string) (string, error)
+}
+
+// SecretProviderFunc 将普通函数转换为 SecretProvider
+type SecretProviderFunc func(ref string) (string, error)
+
+func (f SecretProviderFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+var (
+	secretProvidersMu sync.RWMutex
+	secretProviders   = map[string]SecretProvider{
+		"file": SecretProviderFunc(fileSecret),
+		"env":  SecretProviderFunc(envSecret),
+	}
+)
+
+// RegisterSecretProvider 注册 scheme 对应的 SecretProvider, 需要在 Setup 之前调用
+func RegisterSecretProvider(scheme string, provider SecretProvider) {
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+	secretProviders[scheme] = provider
+}
+
+// fileSecret 读取文件内容, 去掉末尾换行
+func fileSecret(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+func envSecret(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", errors.Errorf("environment variable %s not set", name)
+	}
+	return value, nil
+}
+
+func resolveSecret(value string) (string, error) {
+	i := strings.Index(value, ":")
+	if i <= 0 {
+		return value, nil
+	}
+
+	secretProvidersMu.RLock()
+	provider, ok := secretProviders[value[:i]]
+	secretProvidersMu.RUnlock()
+	if !ok {
+		return value, nil
+	}
+	return provider.Resolve(value[i+1:])
+}
+
+// resolveSecrets 解析 v 中所有标记 secret:"true" 的字符串配置项
+func resolveSecrets(v reflect.Value, namespace string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		return resolveSecrets(v.Elem(), namespace)
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := resolveSecrets(v.Index(i), namespace); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name := strings.TrimPrefix(namespace+"."+field.Name, ".")
+			if field.Tag.Get("secret") == "true" && field.Type.Kind() == reflect.String {
+				secret, err := resolveSecret(v.Field(i).String())
+				if err != nil {
+					return errors.Wrapf(err, "resolve secret %s", name)
+				}
+				v.Field(i).SetString(secret)
+				continue
+			}
+			if err := resolveSecrets(v.Field(i), name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
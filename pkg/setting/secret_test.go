@@ -0,0 +1,96 @@
+package setting
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSecretEnv = "GINEX_TEST_SECRET"
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	writeSecret := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	os.Setenv(testSecretEnv, "from-env")
+	defer os.Unsetenv(testSecretEnv)
+
+	cases := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "literal", value: "plain", want: "plain"},
+		{name: "empty", value: "", want: ""},
+		{name: "unknown scheme", value: "http://example.com", want: "http://example.com"},
+		{name: "empty scheme", value: ":env", want: ":env"},
+		{name: "env", value: "env:" + testSecretEnv, want: "from-env"},
+		{name: "env not set", value: "env:GINEX_TEST_SECRET_NOT_SET", wantErr: true},
+		{name: "file", value: "file:" + writeSecret("plain", "s3cret"), want: "s3cret"},
+		{name: "file trailing newline", value: "file:" + writeSecret("lf", "s3cret\n"), want: "s3cret"},
+		{name: "file trailing crlf", value: "file:" + writeSecret("crlf", "s3cret\r\n"), want: "s3cret"},
+		{name: "file keeps inner colon", value: "file:" + writeSecret("colon", "user:pass"), want: "user:pass"},
+		{name: "file not found", value: "file:" + filepath.Join(dir, "missing"), wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := resolveSecret(c.value)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("resolveSecret(%q) = %q, want error", c.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Fatalf("resolveSecret(%q) = %q, want %q", c.value, got, c.want)
+			}
+		})
+	}
+}
+
+// 只解析标记 secret:"true" 的配置项, 包括列表中的结构体; 失败时错误中包含配置项的路径
+func TestResolveSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replica")
+	if err := ioutil.WriteFile(path, []byte("replica-password\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv(testSecretEnv, "db-password")
+	defer os.Unsetenv(testSecretEnv)
+
+	config := &Config{Database: Database{
+		UserName: "env:" + testSecretEnv,
+		Password: "env:" + testSecretEnv,
+		Replicas: []DatabaseReplica{{Host: "127.0.0.1:3306", Password: "file:" + path}},
+	}}
+	if err := resolveSecrets(reflect.ValueOf(config), ""); err != nil {
+		t.Fatal(err)
+	}
+	if config.Database.Password != "db-password" {
+		t.Errorf("Database.Password = %q, want db-password", config.Database.Password)
+	}
+	if config.Database.Replicas[0].Password != "replica-password" {
+		t.Errorf("Database.Replicas.Password = %q, want replica-password", config.Database.Replicas[0].Password)
+	}
+	if config.Database.UserName != "env:"+testSecretEnv {
+		t.Errorf("Database.UserName without secret tag resolved to %q", config.Database.UserName)
+	}
+
+	config = &Config{Database: Database{Password: "env:GINEX_TEST_SECRET_NOT_SET"}}
+	err := resolveSecrets(reflect.ValueOf(config), "")
+	if err == nil || !strings.Contains(err.Error(), "Database.Password") {
+		t.Fatalf("resolveSecrets = %v, want error for Database.Password", err)
+	}
+}
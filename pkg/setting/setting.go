@@ -1,132 +1,494 @@
 package setting
 
 import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 )
 
+const (
+	// DefaultConfigFile 未指定 --config 时查找的配置文件, 相对于工作目录或可执行文件所在目录
+	DefaultConfigFile = "configs/config.yaml"
+
+	// 环境变量前缀, 如 GINEX_DATABASE_PASSWORD 覆盖 Database.Password
+	envPrefix = "GINEX"
+)
+
 type setting struct {
-	vp  *viper.Viper
-	set map[string]interface{}
+	vp *viper.Viper
+	// 按加载顺序排列, 后加载的文件覆盖先加载的
+	files []string
 }
 
-func New() (*setting, error) {
-	vp := viper.New()
+// New 加载 configFile, profile 不为空时再叠加同目录下的 config.<profile>.yaml,
+// 最后以 GINEX_ 开头的环境变量覆盖文件中的配置
+func New(configFile, profile string) (*setting, error) {
+	base, err := findConfigFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{base}
+	if profile != "" {
+		ext := filepath.Ext(base)
+		overlay := strings.TrimSuffix(base, ext) + "." + profile + ext
+		if _, err := os.Stat(overlay); err != nil {
+			return nil, errors.Wrapf(err, "profile %s", profile)
+		}
+		files = append(files, overlay)
+	}
 
-	vp.SetConfigName("config")
+	vp := viper.New()
 	vp.SetConfigType("yaml")
-	vp.AddConfigPath("configs/")
-	if err := vp.ReadInConfig(); err != nil {
-		return nil, err
+	for i, file := range files {
+		vp.SetConfigFile(file)
+		if i == 0 {
+			err = vp.ReadInConfig()
+		} else {
+			err = vp.MergeInConfig()
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "read config file %s", file)
+		}
+	}
+	bindEnvs(vp, "", reflect.TypeOf(Config{}))
+
+	return &setting{vp: vp, files: files}, nil
+}
+
+func findConfigFile(configFile string) (string, error) {
+	if configFile != "" {
+		return filepath.Abs(configFile)
 	}
 
-	return &setting{vp: vp, set: make(map[string]interface{}, 10)}, nil
+	candidates := []string{DefaultConfigFile}
+	if executable, err := os.Executable(); err == nil {
+		candidates = append(candidates, filepath.Join(filepath.Dir(executable), DefaultConfigFile))
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return filepath.Abs(candidate)
+		}
+	}
+	return "", errors.Errorf("config file not found in %s, use --config to specify", strings.Join(candidates, ", "))
+}
+
+// bindEnvs 为每个配置项绑定环境变量, Database.Password 对应 GINEX_DATABASE_PASSWORD
+func bindEnvs(vp *viper.Viper, prefix string, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Name
+		if prefix != "" {
+			key = prefix + "." + field.Name
+		}
+		if field.Type.Kind() == reflect.Struct {
+			bindEnvs(vp, key, field.Type)
+			continue
+		}
+		// 结构体列表无法用单个环境变量表示
+		if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct {
+			continue
+		}
+		env := envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+		_ = vp.BindEnv(strings.ToLower(key), env)
+	}
 }
 
 type Server struct {
-	RunMode      string
-	HttpPort     string
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
+	RunMode      string        `validate:"oneof=debug release test"`
+	HttpPort     string        `validate:"required,numeric"`
+	ReadTimeout  time.Duration `validate:"gt=0"`
+	WriteTimeout time.Duration `validate:"gt=0"`
+	// 关闭时就绪检查先失败, 等待 ShutdownDrainDelay 后再关闭监听, 留给负载均衡摘除流量
+	ShutdownDrainDelay time.Duration `validate:"gte=0"`
+	// 收到 SIGINT, SIGTERM 后执行全部关闭钩子的总超时时间
+	ShutdownTimeout time.Duration `validate:"gt=0"`
+	// kill -HUP 后等待新进程监听就绪的超时时间, 超时后结束新进程, 当前进程继续提供服务
+	RestartTimeout time.Duration `validate:"gt=0"`
+	// 主服务监听 unix socket 代替 HttpPort (开启 TLS 时代替 HttpsPort)
+	UnixSocket string
+	// unix socket 文件权限, 八进制, 如 0660
+	UnixSocketMode string `validate:"omitempty,numeric"`
+	TLS            TLS
 }
 
 var ServerSetting = &Server{}
 
+// TLS 开启后在 HttpsPort 上提供 HTTPS 及 HTTP/2, RedirectHTTP 为 true 时 HttpPort 只负责跳转到 HTTPS
+type TLS struct {
+	Enable    bool
+	HttpsPort string `validate:"omitempty,numeric"`
+	CertFile  string
+	KeyFile   string
+	// 1.2|1.3
+	MinVersion string `validate:"omitempty,oneof=1.2 1.3"`
+	// intermediate: 兼容 TLS 1.2 的 ECDHE + AEAD 加密套件, modern: 只允许 TLS 1.3
+	CipherPolicy string `validate:"omitempty,oneof=intermediate modern"`
+	// 校验客户端证书的 CA 文件, 用于内部调用方的双向认证
+	ClientCAFile string
+	// none|request|verify-if-given|require
+	ClientAuth   string `validate:"omitempty,oneof=none request verify-if-given require"`
+	RedirectHTTP bool
+	// 检查证书文件是否更新的间隔, 证书更新后无需重启
+	ReloadInterval time.Duration `validate:"gte=0"`
+}
+
 type App struct {
-	DefaultPageSize      int
-	MaxPageSize          int
-	UploadSavePath       string
-	UploadServerUrl      string
-	UploadImageMaxSize   int
-	UploadImageAllowExts []string
+	DefaultPageSize      int      `validate:"min=1"`
+	MaxPageSize          int      `validate:"gtefield=DefaultPageSize"`
+	UploadSavePath       string   `validate:"required"`
+	UploadServerUrl      string   `validate:"omitempty,url"`
+	UploadImageMaxSize   int      `validate:"min=1"` // MB
+	UploadImageAllowExts []string `validate:"required,dive,startswith=."`
 }
 
 var AppSetting = &App{}
 
 type JWT struct {
-	Secret string
-	Issuer string
-	Expire time.Duration
+	Secret string        `validate:"required" secret:"true"`
+	Issuer string        `validate:"required"`
+	Expire time.Duration `validate:"gt=0"`
 }
 
 var JWTSetting = &JWT{}
 
 type Logger struct {
-	Level    string
+	Level string `validate:"oneof=debug info warn warning error panic fatal"`
+	// gin 路由日志, 访问日志和 gorm 日志的级别, 为空时与 Level 相同
+	GinRouteLevel string `validate:"omitempty,oneof=debug info warn warning error panic fatal"`
+	AccessLevel   string `validate:"omitempty,oneof=debug info warn warning error panic fatal"`
+	GormLevel     string `validate:"omitempty,oneof=debug info warn warning error panic fatal"`
+	// 未单独配置 Outputs 的日志使用 Stdout, FilePath
 	Stdout   bool
-	FilePath string
+	FilePath string `validate:"required_without=Stdout"`
+
+	// 各日志的编码及输出配置
+	App      LogSink
+	Access   LogSink
+	GinRoute LogSink
+	Gorm     LogSink
+
+	AccessOptions AccessOptions
+}
+
+// AccessOptions 访问日志记录内容
+type AccessOptions struct {
+	// 需要脱敏的参数名, 为空时使用 password, appSecret, token
+	RedactFields []string
+	// 记录出错请求的请求体和响应体, 最多 MaxBodySize 字节
+	CaptureBody bool
+	MaxBodySize int `validate:"min=0"`
+	// 成功请求每秒记录前 SampleInitial 条, 之后每 SampleThereafter 条记录一条, 为 0 时不采样
+	SampleInitial    int `validate:"min=0"`
+	SampleThereafter int `validate:"min=0"`
+}
+
+type LogSink struct {
+	// json | console, 默认 json
+	Encoder string `validate:"omitempty,oneof=json console"`
+	// iso8601 | datetime (2006-01-02 15:04:05.000) | rfc3339 | epoch | epochmillis, 默认 iso8601
+	TimeFormat string      `validate:"omitempty,oneof=iso8601 datetime rfc3339 epoch epochmillis"`
+	Outputs    []LogOutput `validate:"dive"`
+}
+
+// LogOutput 日志输出位置, 同一个文件只按第一次出现的配置轮转
+type LogOutput struct {
+	// stdout | stderr | file
+	Type       string `validate:"oneof=stdout stderr file"`
+	FilePath   string
+	MaxSize    int `validate:"min=0"` // 每个日志文件保存的最大尺寸 单位：M, 默认 128
+	MaxBackups int `validate:"min=0"` // 日志文件最多保存多少个备份, 默认 30
+	MaxAge     int `validate:"min=0"` // 文件最多保存多少天, 默认 7
+	Compress   bool
 }
 
 var LoggerSetting = &Logger{}
 
+// Database 按 DBType 选择驱动, 连接参数按 DBType 校验, 见 validateDatabase
 type Database struct {
-	DBType       string
+	// mysql|postgres|sqlite
+	DBType      string `validate:"oneof=mysql postgres sqlite"`
+	UserName    string
+	Password    string `secret:"true"`
+	Host        string
+	DBName      string
+	TablePrefix string
+	// mysql 连接参数
+	Charset   string
+	ParseTime bool
+	// postgres, sqlite 连接参数
+	Postgres PostgresOptions
+	SQLite   SQLiteOptions
+
+	MaxIdleConns int `validate:"min=0"`
+	MaxOpenConns int `validate:"min=0"`
+	// 连接的最长使用时间及最长空闲时间, 超过后关闭, 主从切换后旧连接不会一直保留; 0 不限制
+	ConnMaxLifetime time.Duration `validate:"gte=0"`
+	ConnMaxIdleTime time.Duration `validate:"gte=0"`
+	// 建立连接的超时时间, sqlite 不使用
+	ConnectTimeout time.Duration `validate:"gte=0"`
+	// 启动时连接失败的重试次数, 等待时间从 ConnectRetryBackoff 开始每次翻倍, 最长 ConnectRetryMaxBackoff
+	ConnectRetries         int           `validate:"min=0"`
+	ConnectRetryBackoff    time.Duration `validate:"gte=0"`
+	ConnectRetryMaxBackoff time.Duration `validate:"gte=0"`
+	// 单条语句默认的超时时间, ctx 已设置 deadline 时以 ctx 为准; 0 不限制
+	QueryTimeout time.Duration `validate:"gte=0"`
+	// 只读副本, 事务之外的查询轮询发送到健康的副本, 写操作及事务使用主库; 仅支持 mysql, postgres
+	Replicas []DatabaseReplica `validate:"dive"`
+	// 副本健康检查间隔, 检查失败的副本移出轮询, 恢复后重新加入; 不能关闭, 否则不可用的副本一直留在轮询中
+	ReplicaHealthCheckInterval time.Duration `validate:"gt=0"`
+	// 事务遇到死锁, 序列化失败时的重试次数及首次重试前的等待时间, 之后每次翻倍
+	TxMaxRetries   int           `validate:"min=0"`
+	TxRetryBackoff time.Duration `validate:"gte=0"`
+	// 启动时执行未执行的 Migration, 多个实例同时启动时只有一个实例执行
+	AutoMigrate bool
+
+	GormForceGormZapLog bool
+	// 慢查询阈值, 纯数字按毫秒处理
+	GormLogSlowThreshold Milliseconds `validate:"min=0"`
+}
+
+// DatabaseReplica 只读副本, UserName, Password 为空时使用主库的账号, DBName 与主库相同
+type DatabaseReplica struct {
+	Host         string `validate:"required,hostname_port"`
 	UserName     string
-	Password     string
-	Host         string
-	DBName       string
-	TablePrefix  string
-	Charset      string
-	ParseTime    bool
-	MaxIdleConns int
-	MaxOpenConns int
+	Password     string `secret:"true"`
+	MaxIdleConns int    `validate:"min=0"`
+	MaxOpenConns int    `validate:"min=0"`
+}
 
-	GormForceGormZapLog  bool
-	GormLogSlowThreshold time.Duration
+type PostgresOptions struct {
+	// disable|require|verify-ca|verify-full
+	SSLMode  string `validate:"omitempty,oneof=disable require verify-ca verify-full"`
+	TimeZone string
+}
+
+type SQLiteOptions struct {
+	// 数据库文件路径, :memory: 为内存数据库
+	Path        string
+	BusyTimeout time.Duration `validate:"gte=0"`
+	// delete|wal
+	JournalMode string `validate:"omitempty,oneof=delete wal"`
 }
 
 var DatabaseSetting = &Database{}
 
+// SessionRedis 的地址按 Type 校验, 见 validateSessionRedis
 type SessionRedis struct {
-	Type      string
+	// singlePoint|cluster|sentinel
+	Type      string `validate:"oneof=singlePoint cluster sentinel"`
 	Address   string
 	Addresses []string
-	Password  string
+	// sentinel 模式的主节点名称及 sentinel 地址
+	MasterName        string
+	SentinelAddresses []string
+	SentinelPassword  string `secret:"true"`
+	// redis 6 ACL 用户名, 为空时只使用密码认证
+	Username string
+	Password string `secret:"true"`
+	// 数据库编号, cluster 模式只能为 0
+	DB  int `validate:"min=0"`
+	TLS RedisTLS
+	// 连接池及超时, 0 使用 go-redis 的默认值
+	PoolSize     int           `validate:"min=0"`
+	MinIdleConns int           `validate:"min=0"`
+	DialTimeout  time.Duration `validate:"gte=0"`
+	ReadTimeout  time.Duration `validate:"gte=0"`
+	WriteTimeout time.Duration `validate:"gte=0"`
+	PoolTimeout  time.Duration `validate:"gte=0"`
+	IdleTimeout  time.Duration `validate:"gte=0"`
+	// 启动时连接失败的重试次数, 等待时间从 ConnectRetryBackoff 开始每次翻倍, 最长 ConnectRetryMaxBackoff
+	ConnectRetries         int           `validate:"min=0"`
+	ConnectRetryBackoff    time.Duration `validate:"gte=0"`
+	ConnectRetryMaxBackoff time.Duration `validate:"gte=0"`
+}
+
+// RedisTLS CAFile 为空时使用系统根证书, CertFile, KeyFile 用于双向认证, 需要同时配置
+type RedisTLS struct {
+	Enable             bool
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
 }
 
 var SessionRedisSetting = &SessionRedis{}
 
-func (s *setting) createSection() {
-	s.set = map[string]interface{}{
-		"Server":       ServerSetting,
-		"App":          AppSetting,
-		"JWT":          JWTSetting,
-		"Log":          LoggerSetting,
-		"Database":     DatabaseSetting,
-		"SessionRedis": SessionRedisSetting,
+// Cache 通用缓存, redis 与 session 共用 SessionRedis 的连接
+type Cache struct {
+	// memory|redis|tiered, tiered 为进程内 LRU 在前, redis 在后
+	Type       string `validate:"oneof=memory redis tiered"`
+	KeyPrefix  string
+	DefaultTTL time.Duration `validate:"gt=0"`
+	// 进程内 LRU 的最大条目数
+	MemoryMaxEntries int `validate:"min=1"`
+	// tiered 模式下进程内缓存的最长时间, 其他实例修改数据后本实例最多延迟此时间
+	MemoryTTL time.Duration `validate:"gte=0"`
+}
+
+var CacheSetting = &Cache{}
+
+type Session struct {
+	Name   string        `validate:"required"`
+	MaxAge time.Duration `validate:"gt=0"`
+	// redis|memory|database|filesystem|cookie, memory 只适用于单实例, cookie 无法在服务端使 session 失效
+	Store string `validate:"oneof=redis memory database filesystem cookie"`
+	// filesystem 保存 session 文件的目录, 为空时使用系统临时目录
+	FilesystemPath string
+	// memory, database, filesystem 删除过期 session 的间隔, 0 不删除
+	CleanupInterval time.Duration `validate:"gte=0"`
+	// 第一组密钥用于签名加密新的 cookie, 全部密钥都可以用于解密, 轮换时把新密钥放在最前面
+	KeyPairs []SessionKeyPair `validate:"required,dive"`
+}
+
+// SessionKeyPair secure cookie 的签名密钥及加密密钥, base64 编码
+type SessionKeyPair struct {
+	HashKey  string `validate:"required,base64" secret:"true"`
+	BlockKey string `validate:"omitempty,base64" secret:"true"` // 解码后长度为 16, 24 或 32 字节
+}
+
+// Keys 返回 securecookie.CodecsFromPairs 使用的密钥
+func (s Session) Keys() [][]byte {
+	keys := make([][]byte, 0, len(s.KeyPairs)*2)
+	for _, pair := range s.KeyPairs {
+		hashKey, _ := base64.StdEncoding.DecodeString(pair.HashKey)
+		blockKey, _ := base64.StdEncoding.DecodeString(pair.BlockKey)
+		if len(blockKey) == 0 {
+			blockKey = nil
+		}
+		keys = append(keys, hashKey, blockKey)
+	}
+	return keys
+}
+
+var SessionSetting = &Session{}
+
+// Config 配置文件中全部的配置段
+type Config struct {
+	Server       Server
+	App          App
+	JWT          JWT
+	Log          Logger
+	Database     Database
+	SessionRedis SessionRedis
+	Cache        Cache
+	Session      Session
+}
+
+func (s *setting) readConfig() (*Config, error) {
+	config, err := s.decode()
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveSecrets(reflect.ValueOf(config), ""); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// decode 读取配置, secret 引用保持原样
+func (s *setting) decode() (*Config, error) {
+	config := &Config{}
+	if err := s.vp.Unmarshal(config, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		stringToDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+	))); err != nil {
+		return nil, err
 	}
+	return config, nil
 }
 
-func (s *setting) readSection(key string, rawVal interface{}) error {
-	if err := s.vp.UnmarshalKey(key, rawVal); err != nil {
+var (
+	// 启动时指定的配置文件及 profile, 热加载时按相同方式重新读取
+	configFile string
+	profile    string
+
+	// current 为最近一次成功加载的配置来源, 热加载时监听其中的所有文件
+	current *setting
+)
+
+func Setup(file, profileName string) error {
+	s, err := New(file, profileName)
+	if err != nil {
+		return err
+	}
+
+	config, err := s.readConfig()
+	if err != nil {
+		return err
+	}
+	if err := config.validate(); err != nil {
 		return err
 	}
+
+	apply(config)
+	configFile, profile, current = file, profileName, s
+
 	return nil
 }
 
-func Setup() error {
-	s, err := New()
+// SetupOffline 只读取配置, 不解析 secret 引用也不校验, 用于不启动服务的命令 (如 routes),
+// 使其在没有密钥的环境中也可以执行. 加载的配置不支持热加载, 不能用于连接数据库, redis
+func SetupOffline(file, profileName string) error {
+	s, err := New(file, profileName)
 	if err != nil {
 		return err
 	}
-	s.createSection()
-
-	for key, setting := range s.set {
-		if err := s.readSection(key, setting); err != nil {
-			return err
-		}
-		switch key {
-		case "Server":
-			s := reflect.ValueOf(setting).Elem().Addr().Interface().(*Server)
-			s.ReadTimeout *= time.Second
-			s.WriteTimeout *= time.Second
-		case "JWT":
-			j := reflect.ValueOf(setting).Elem().Addr().Interface().(*JWT)
-			j.Expire *= time.Second
-		}
+	config, err := s.decode()
+	if err != nil {
+		return err
 	}
 
+	apply(config)
 	return nil
 }
+
+func apply(config *Config) {
+	mu.Lock()
+	defer mu.Unlock()
+	*ServerSetting = config.Server
+	*AppSetting = config.App
+	*JWTSetting = config.JWT
+	*LoggerSetting = config.Log
+	*DatabaseSetting = config.Database
+	*SessionRedisSetting = config.SessionRedis
+	*CacheSetting = config.Cache
+	*SessionSetting = config.Session
+}
+
+// ConfigFiles 返回已加载的配置文件, 按覆盖顺序排列
+func ConfigFiles() []string {
+	if current == nil {
+		return nil
+	}
+	return append([]string{}, current.files...)
+}
+
+// Watch 监听已加载的配置文件变更并热加载, 热加载失败时保留旧配置并回调 onError
+func Watch(onError func(err error)) {
+	for _, file := range ConfigFiles() {
+		vp := viper.New()
+		vp.SetConfigFile(file)
+		if err := vp.ReadInConfig(); err != nil {
+			if onError != nil {
+				onError(errors.Wrapf(err, "watch %s", file))
+			}
+			continue
+		}
+		vp.OnConfigChange(func(in fsnotify.Event) {
+			if err := Reload(); err != nil && onError != nil {
+				onError(errors.Wrapf(err, "reload %s", in.Name))
+			}
+		})
+		vp.WatchConfig()
+	}
+}
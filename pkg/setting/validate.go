@@ -0,0 +1,213 @@
+package setting
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mitchellh/mapstructure"
+)
+
+// ValidationErrors 汇总配置校验失败的全部配置项
+type ValidationErrors []string
+
+func (v ValidationErrors) Error() string {
+	return fmt.Sprintf("invalid configuration (%d errors):\n  %s", len(v), strings.Join(v, "\n  "))
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterStructValidation(validateSessionRedis, SessionRedis{})
+	v.RegisterStructValidation(validateSessionKeyPair, SessionKeyPair{})
+	v.RegisterStructValidation(validateLogOutput, LogOutput{})
+	v.RegisterStructValidation(validateTLS, TLS{})
+	v.RegisterStructValidation(validateDatabase, Database{})
+	return v
+}
+
+// validateDatabase mysql, postgres 需要连接地址及账号, sqlite 需要数据库文件路径且不支持副本
+func validateDatabase(sl validator.StructLevel) {
+	database := sl.Current().Interface().(Database)
+
+	switch database.DBType {
+	case "mysql", "postgres":
+		if err := sl.Validator().Var(database.Host, "required,hostname_port"); err != nil {
+			sl.ReportError(database.Host, "Host", "Host", "hostname_port", "")
+		}
+		if database.UserName == "" {
+			sl.ReportError(database.UserName, "UserName", "UserName", "required", "")
+		}
+		if database.DBName == "" {
+			sl.ReportError(database.DBName, "DBName", "DBName", "required", "")
+		}
+		if database.DBType == "mysql" && database.Charset == "" {
+			sl.ReportError(database.Charset, "Charset", "Charset", "required", "")
+		}
+	case "sqlite":
+		if database.SQLite.Path == "" {
+			sl.ReportError(database.SQLite.Path, "SQLite.Path", "Path", "required", "")
+		}
+		if len(database.Replicas) > 0 {
+			sl.ReportError(database.Replicas, "Replicas", "Replicas", "excluded_with_sqlite", "")
+		}
+	}
+}
+
+// validateTLS 开启 TLS 时需要证书, 私钥及端口, 校验客户端证书时需要 ClientCAFile
+func validateTLS(sl validator.StructLevel) {
+	tls := sl.Current().Interface().(TLS)
+	if !tls.Enable {
+		return
+	}
+
+	for name, value := range map[string]string{
+		"HttpsPort": tls.HttpsPort,
+		"CertFile":  tls.CertFile,
+		"KeyFile":   tls.KeyFile,
+	} {
+		if value == "" {
+			sl.ReportError(value, name, name, "required", "")
+		}
+	}
+	switch tls.ClientAuth {
+	case "verify-if-given", "require":
+		if tls.ClientCAFile == "" {
+			sl.ReportError(tls.ClientCAFile, "ClientCAFile", "ClientCAFile", "required", "")
+		}
+	}
+	if tls.CipherPolicy == "modern" && tls.MinVersion == "1.2" {
+		sl.ReportError(tls.MinVersion, "MinVersion", "MinVersion", "oneof", "1.3")
+	}
+}
+
+// validateLogOutput file 类型需要 FilePath
+func validateLogOutput(sl validator.StructLevel) {
+	output := sl.Current().Interface().(LogOutput)
+
+	if output.Type == "file" && output.FilePath == "" {
+		sl.ReportError(output.FilePath, "FilePath", "FilePath", "required", "")
+	}
+}
+
+// validateSessionKeyPair 加密密钥长度必须为 16, 24 或 32 字节, 分别对应 AES-128, AES-192, AES-256
+func validateSessionKeyPair(sl validator.StructLevel) {
+	pair := sl.Current().Interface().(SessionKeyPair)
+
+	blockKey, err := base64.StdEncoding.DecodeString(pair.BlockKey)
+	if err != nil {
+		return
+	}
+	switch len(blockKey) {
+	case 0, 16, 24, 32:
+	default:
+		sl.ReportError(pair.BlockKey, "BlockKey", "BlockKey", "aes_key_length", strconv.Itoa(len(blockKey)))
+	}
+}
+
+// validateSessionRedis singlePoint 模式校验 Address, cluster 模式校验 Addresses, sentinel 模式校验 MasterName, SentinelAddresses
+func validateSessionRedis(sl validator.StructLevel) {
+	redis := sl.Current().Interface().(SessionRedis)
+
+	switch redis.Type {
+	case "singlePoint":
+		if err := sl.Validator().Var(redis.Address, "required,hostname_port"); err != nil {
+			sl.ReportError(redis.Address, "Address", "Address", "hostname_port", "")
+		}
+	case "cluster":
+		if err := sl.Validator().Var(redis.Addresses, "min=1,dive,hostname_port"); err != nil {
+			sl.ReportError(redis.Addresses, "Addresses", "Addresses", "hostname_port", "")
+		}
+		if redis.DB != 0 {
+			sl.ReportError(redis.DB, "DB", "DB", "eq=0", "")
+		}
+	case "sentinel":
+		if redis.MasterName == "" {
+			sl.ReportError(redis.MasterName, "MasterName", "MasterName", "required", "")
+		}
+		if err := sl.Validator().Var(redis.SentinelAddresses, "min=1,dive,hostname_port"); err != nil {
+			sl.ReportError(redis.SentinelAddresses, "SentinelAddresses", "SentinelAddresses", "hostname_port", "")
+		}
+	}
+
+	if redis.TLS.Enable && (redis.TLS.CertFile == "") != (redis.TLS.KeyFile == "") {
+		sl.ReportError(redis.TLS.KeyFile, "TLS.KeyFile", "KeyFile", "required_with=CertFile", "")
+	}
+}
+
+// validate 校验全部配置段, 一次性返回所有不合法的配置项
+func (c *Config) validate() error {
+	err := validate.Struct(c)
+	if err == nil {
+		return nil
+	}
+	fieldErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	errs := make(ValidationErrors, 0, len(fieldErrors))
+	for _, e := range fieldErrors {
+		field := strings.TrimPrefix(e.Namespace(), "Config.")
+		rule := e.Tag()
+		if e.Param() != "" {
+			rule += "=" + e.Param()
+		}
+		errs = append(errs, fmt.Sprintf("%s: value %v does not satisfy '%s'", field, e.Value(), rule))
+	}
+	return errs
+}
+
+// Milliseconds 纯数字按毫秒处理的时间配置, 兼容原有的 GormLogSlowThreshold: 100 (100ms) 写法
+type Milliseconds time.Duration
+
+// Duration 转换为 time.Duration
+func (m Milliseconds) Duration() time.Duration {
+	return time.Duration(m)
+}
+
+// stringToDurationHookFunc 时间配置支持 60s, 2h, 100ms 等格式, 纯数字按秒处理, Milliseconds 类型的纯数字按毫秒处理
+func stringToDurationHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		var unit time.Duration
+		switch t {
+		case reflect.TypeOf(time.Duration(0)):
+			unit = time.Second
+		case reflect.TypeOf(Milliseconds(0)):
+			unit = time.Millisecond
+		default:
+			return data, nil
+		}
+
+		var d time.Duration
+		switch f.Kind() {
+		case reflect.String:
+			s := strings.TrimSpace(data.(string))
+			if n, err := strconv.ParseFloat(s, 64); err == nil {
+				d = time.Duration(n * float64(unit))
+				break
+			}
+			parsed, err := time.ParseDuration(s)
+			if err != nil {
+				return nil, err
+			}
+			d = parsed
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			d = time.Duration(reflect.ValueOf(data).Int()) * unit
+		case reflect.Float32, reflect.Float64:
+			d = time.Duration(reflect.ValueOf(data).Float() * float64(unit))
+		default:
+			return data, nil
+		}
+
+		if t == reflect.TypeOf(Milliseconds(0)) {
+			return Milliseconds(d), nil
+		}
+		return d, nil
+	}
+}
@@ -0,0 +1,169 @@
+package setting
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// loadDefaultConfig 读取仓库中的默认配置, secret 引用替换为合法的值, 作为校验的基准
+func loadDefaultConfig(t *testing.T) *Config {
+	t.Helper()
+	s, err := New(filepath.Join("..", "..", DefaultConfigFile), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := s.decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Session.KeyPairs = []SessionKeyPair{{
+		HashKey:  "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+		BlockKey: "MDEyMzQ1Njc4OWFiY2RlZg==",
+	}}
+	return config
+}
+
+// 一次校验返回全部配置段中不合法的配置项
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(c *Config)
+		fields []string
+	}{
+		{name: "default config", modify: func(c *Config) {}},
+		{
+			name: "multiple sections",
+			modify: func(c *Config) {
+				c.Server.HttpPort = ""
+				c.Server.ReadTimeout = 0
+				c.App.DefaultPageSize = 0
+				c.Log.Level = "verbose"
+			},
+			fields: []string{"App.DefaultPageSize", "Log.Level", "Server.HttpPort", "Server.ReadTimeout"},
+		},
+		{
+			name: "mysql connection",
+			modify: func(c *Config) {
+				c.Database.Host = "localhost"
+				c.Database.UserName = ""
+				c.Database.DBName = ""
+				c.Database.Charset = ""
+			},
+			fields: []string{"Database.Charset", "Database.DBName", "Database.Host", "Database.UserName"},
+		},
+		{
+			name: "sqlite replicas",
+			modify: func(c *Config) {
+				c.Database.DBType = "sqlite"
+				c.Database.SQLite.Path = ""
+				c.Database.Replicas = []DatabaseReplica{{Host: "127.0.0.1:3306"}}
+			},
+			fields: []string{"Database.Replicas", "Database.SQLite.Path"},
+		},
+		{
+			name: "tls",
+			modify: func(c *Config) {
+				c.Server.TLS = TLS{Enable: true, ClientAuth: "require", CipherPolicy: "modern", MinVersion: "1.2"}
+			},
+			fields: []string{
+				"Server.TLS.CertFile", "Server.TLS.ClientCAFile", "Server.TLS.HttpsPort",
+				"Server.TLS.KeyFile", "Server.TLS.MinVersion",
+			},
+		},
+		{
+			name: "session keys",
+			modify: func(c *Config) {
+				c.Session.KeyPairs = []SessionKeyPair{{HashKey: "not base64", BlockKey: "MDEyMzQ1Njc4OQ=="}}
+			},
+			fields: []string{"Session.KeyPairs[0].BlockKey", "Session.KeyPairs[0].HashKey"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config := loadDefaultConfig(t)
+			c.modify(config)
+
+			err := config.validate()
+			if len(c.fields) == 0 {
+				if err != nil {
+					t.Fatalf("validate: %v", err)
+				}
+				return
+			}
+			errs, ok := err.(ValidationErrors)
+			if !ok {
+				t.Fatalf("validate = %v, want ValidationErrors", err)
+			}
+			fields := make([]string, 0, len(errs))
+			for _, e := range errs {
+				fields = append(fields, e[:strings.Index(e, ":")])
+			}
+			sort.Strings(fields)
+			if !reflect.DeepEqual(fields, c.fields) {
+				t.Fatalf("invalid fields = %v, want %v\n%v", fields, c.fields, err)
+			}
+		})
+	}
+}
+
+// 纯数字的 time.Duration 按秒处理, Milliseconds 按毫秒处理, 带单位时两者相同
+func TestStringToDurationHook(t *testing.T) {
+	cases := []struct {
+		name         string
+		value        interface{}
+		duration     time.Duration
+		milliseconds time.Duration
+	}{
+		{name: "bare string", value: "100", duration: 100 * time.Second, milliseconds: 100 * time.Millisecond},
+		{name: "bare int", value: 100, duration: 100 * time.Second, milliseconds: 100 * time.Millisecond},
+		{name: "bare float", value: 1.5, duration: 1500 * time.Millisecond, milliseconds: 1500 * time.Microsecond},
+		{name: "fractional string", value: "0.5", duration: 500 * time.Millisecond, milliseconds: 500 * time.Microsecond},
+		{name: "spaces", value: " 30 ", duration: 30 * time.Second, milliseconds: 30 * time.Millisecond},
+		{name: "milliseconds unit", value: "100ms", duration: 100 * time.Millisecond, milliseconds: 100 * time.Millisecond},
+		{name: "hours unit", value: "2h", duration: 2 * time.Hour, milliseconds: 2 * time.Hour},
+		{name: "zero", value: "0", duration: 0, milliseconds: 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got struct {
+				Duration     time.Duration
+				Milliseconds Milliseconds
+			}
+			if err := decodeDurations(map[string]interface{}{"Duration": c.value, "Milliseconds": c.value}, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Duration != c.duration {
+				t.Errorf("time.Duration = %v, want %v", got.Duration, c.duration)
+			}
+			if got.Milliseconds.Duration() != c.milliseconds {
+				t.Errorf("Milliseconds = %v, want %v", got.Milliseconds.Duration(), c.milliseconds)
+			}
+		})
+	}
+
+	for _, value := range []string{"abc", "10 minutes", "1x"} {
+		var got struct{ Duration time.Duration }
+		if err := decodeDurations(map[string]interface{}{"Duration": value}, &got); err == nil {
+			t.Errorf("decode %q: want error, got %v", value, got.Duration)
+		}
+	}
+}
+
+func decodeDurations(input map[string]interface{}, result interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: stringToDurationHookFunc(),
+		Result:     result,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(input)
+}
@@ -2,6 +2,7 @@ package upload
 
 import (
 	"io"
+	"io/ioutil"
 	"mime/multipart"
 	"os"
 	"path"
@@ -19,7 +20,7 @@ const (
 )
 
 func GetStoragePath() string {
-	return setting.AppSetting.UploadSavePath
+	return setting.GetAppSetting().UploadSavePath
 }
 
 func EncryptionFileName(name string) string {
@@ -38,7 +39,7 @@ func CheckContainExt(t FileType, name string) bool {
 	ext := strings.ToUpper(path.Ext(name))
 	switch t {
 	case TypeImage:
-		for _, allowExt := range setting.AppSetting.UploadImageAllowExts {
+		for _, allowExt := range setting.GetAppSetting().UploadImageAllowExts {
 			if strings.ToUpper(allowExt) == ext {
 				return true
 			}
@@ -62,7 +63,7 @@ func CheckMaxSize(t FileType, f multipart.File) bool {
 
 	switch t {
 	case TypeImage:
-		if size <= setting.AppSetting.UploadImageMaxSize*1024*1024 {
+		if size <= setting.GetAppSetting().UploadImageMaxSize*1024*1024 {
 			return true
 		}
 	}
@@ -74,6 +75,16 @@ func CheckPermission(dst string) bool {
 	return os.IsPermission(err)
 }
 
+// CheckWritable 在 dst 目录中创建并删除临时文件, 检查目录是否可写
+func CheckWritable(dst string) error {
+	f, err := ioutil.TempFile(dst, ".writable-")
+	if err != nil {
+		return err
+	}
+	_ = f.Close()
+	return os.Remove(f.Name())
+}
+
 func CreateStoragePath(storagePath string, permission os.FileMode) error {
 	if err := os.MkdirAll(storagePath, permission); err != nil {
 		return err
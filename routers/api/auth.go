@@ -31,7 +31,7 @@ func GetAuth(c *gin.Context) {
 		return
 	}
 
-	if err := service.CheckAuth(form.AuthKey, form.AuthSecret); err != nil {
+	if err := service.CheckAuth(c.Request.Context(), form.AuthKey, form.AuthSecret); err != nil {
 		appG.Response(http.StatusUnauthorized, errcode.AuthNotExistError.WithDetails(err.Error()), struct{}{})
 		return
 	}
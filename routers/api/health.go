@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gin-example/pkg/health"
+)
+
+// @Summary 存活检查, 进程可以处理请求即返回 200
+// @Produce json
+// @Success 200 {object} health.Report
+// @Router /healthz [get]
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, health.Report{Status: health.StatusUp, Checks: map[string]health.CheckResult{}})
+}
+
+// @Summary 就绪检查, 检查数据库, Redis, 上传目录, 任一不可用或服务正在关闭时返回 503
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func Readyz(c *gin.Context) {
+	report := health.Ready(c.Request.Context())
+	if report.Status != health.StatusUp {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
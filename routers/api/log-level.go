@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gin-example/pkg/app"
+	"gin-example/pkg/errcode"
+	"gin-example/service"
+)
+
+// @Summary 获取日志级别
+// @Produce json
+// @Success 200 {object} service.LogLevelResponse
+// @Router /admin/log/level [get]
+func GetLogLevel(c *gin.Context) {
+	appG := app.Gin{Context: c}
+	appG.ResponseSuccess(http.StatusOK, service.GetLogLevels())
+}
+
+/*
+	临时开启 10 分钟 debug 日志
+	curl -X PUT "http://127.0.0.1:8000/admin/log/level" -H "Content-Type: application/json" -d '
+	{
+		"logger": "app",
+		"level": "debug",
+		"duration": "10m"
+	}'
+*/
+type SetLogLevelForm struct {
+	Logger   string `form:"logger" binding:"required,oneof=app access gin gorm"`
+	Level    string `form:"level" binding:"required,oneof=debug info warn error"`
+	Duration string `form:"duration" binding:""`
+}
+
+// @Summary 修改日志级别
+// @Produce json
+// @Param logger body string true "日志" Enums(app,access,gin,gorm)
+// @Param level body string true "级别" Enums(debug,info,warn,error)
+// @Param duration body string false "有效期, 如 10m, 为空时永久生效"
+// @Success 200 {object} service.LogLevelResponse
+// @Failure 500 {object} app.Response
+// @Router /admin/log/level [put]
+func SetLogLevel(c *gin.Context) {
+	appG := app.Gin{Context: c}
+	form := SetLogLevelForm{}
+
+	if err := app.BindAndValid(c, &form); err != nil {
+		appG.Response(http.StatusBadRequest, errcode.InvalidParamsError.WithDetails(err.Error()), struct{}{})
+		return
+	}
+
+	logLevelResponse, err := service.SetLogLevel(form.Logger, form.Level, form.Duration)
+	if err != nil {
+		appG.Response(http.StatusBadRequest, errcode.SetLogLevelError.WithDetails(err.Error()), struct{}{})
+		return
+	}
+	appG.ResponseSuccess(http.StatusOK, logLevelResponse)
+}
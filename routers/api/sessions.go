@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
+
+	"gin-example/middleware/session-auth"
+	"gin-example/pkg/app"
+	"gin-example/pkg/convert"
+	"gin-example/pkg/errcode"
+)
+
+// 下线的 session 数量
+type RevokeSessionsResult struct {
+	Revoked int `json:"revoked"`
+}
+
+// curl -X GET "http://127.0.0.1:8000/api/v1/sessions"
+
+// @Summary 当前用户的登录设备
+// @Produce json
+// @Success 200 {array} sessionauth.UserSession
+// @Failure 500 {object} app.Response
+// @Router /api/v1/sessions [get]
+func GetSessions(c *gin.Context) {
+	appG := app.Gin{Context: c}
+
+	userSessions, err := sessionauth.ListUserSessions(c)
+	if err != nil {
+		appG.Response(sessionErrorStatus(err), errcode.GetSessionsError.WithDetails(err.Error()), struct{}{})
+		return
+	}
+	appG.ResponseSuccess(http.StatusOK, userSessions)
+}
+
+// curl -X DELETE "http://127.0.0.1:8000/api/v1/sessions/<id>"
+
+// @Summary 下线当前用户的一个登录设备
+// @Produce json
+// @Param id path string true "session id"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /api/v1/sessions/{id} [delete]
+func DeleteSession(c *gin.Context) {
+	appG := app.Gin{Context: c}
+
+	err := sessionauth.RevokeUserSession(c, c.Param("id"))
+	if errors.Is(err, sessionauth.ErrSessionNotFound) {
+		appG.Response(http.StatusNotFound, errcode.SessionNotFoundError, struct{}{})
+		return
+	}
+	if err != nil {
+		appG.Response(sessionErrorStatus(err), errcode.RevokeSessionError.WithDetails(err.Error()), struct{}{})
+		return
+	}
+	appG.Response(http.StatusOK, errcode.Success, struct{}{})
+}
+
+// curl -X DELETE "http://127.0.0.1:8000/api/v1/sessions"
+
+// @Summary 下线当前用户的其他登录设备
+// @Produce json
+// @Success 200 {object} RevokeSessionsResult
+// @Failure 500 {object} app.Response
+// @Router /api/v1/sessions [delete]
+func DeleteOtherSessions(c *gin.Context) {
+	appG := app.Gin{Context: c}
+
+	n, err := sessionauth.RevokeOtherUserSessions(c)
+	if err != nil {
+		appG.Response(sessionErrorStatus(err), errcode.RevokeSessionError.WithDetails(err.Error()), struct{}{})
+		return
+	}
+	appG.ResponseSuccess(http.StatusOK, RevokeSessionsResult{Revoked: n})
+}
+
+// curl -X DELETE "http://127.0.0.1:8000/admin/users/2/sessions"
+
+// @Summary 下线用户的全部登录设备
+// @Produce json
+// @Param id path int true "用户id"
+// @Success 200 {object} RevokeSessionsResult
+// @Failure 500 {object} app.Response
+// @Router /admin/users/{id}/sessions [delete]
+func DeleteUserSessions(c *gin.Context) {
+	appG := app.Gin{Context: c}
+	id := convert.StrTo(c.Param("id")).MustInt()
+	if err := validator.New().Var(id, "gte=1"); err != nil {
+		appG.Response(http.StatusBadRequest, errcode.InvalidParamsError.WithDetails(err.Error()), struct{}{})
+		return
+	}
+
+	n, err := sessionauth.RevokeAllUserSessions(c.Request.Context(), uint(id))
+	if err != nil {
+		appG.Response(sessionErrorStatus(err), errcode.RevokeSessionError.WithDetails(err.Error()), struct{}{})
+		return
+	}
+	appG.ResponseSuccess(http.StatusOK, RevokeSessionsResult{Revoked: n})
+}
+
+func sessionErrorStatus(err error) int {
+	if errors.Is(err, sessionauth.ErrUserSessionsNotSupported) {
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"gin-example/pkg/app"
 	"gin-example/pkg/errcode"
+	"gin-example/pkg/lifecycle"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"time"
@@ -16,18 +17,26 @@ import (
 // @Failure 500 {object} app.Response
 // @Router /stream [get]
 func Stream(c *gin.Context) {
-	appG := app.Gin{Context: c,}
-	i := 0
-	for t := range time.Tick(1*time.Second) {
-		if i > 5 {
-			break
+	appG := app.Gin{Context: c}
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for i := 0; i <= 5; i++ {
+		var t time.Time
+		select {
+		case t = <-ticker.C:
+		// 服务器关闭或客户端断开时提前结束
+		case <-lifecycle.ShuttingDown():
+			appG.ResponseSuccess(http.StatusOK, struct{}{})
+			return
+		case <-c.Request.Context().Done():
+			return
 		}
-		if _, err := c.Writer.WriteString(t.String()+"\n"); err != nil {
-			appG.Response(http.StatusInternalServerError, errcode.EditTagError, struct {}{})
+		if _, err := c.Writer.WriteString(t.String() + "\n"); err != nil {
+			appG.Response(http.StatusInternalServerError, errcode.EditTagError, struct{}{})
 			return
 		}
 		c.Writer.Flush()
-		i++
 	}
-	appG.ResponseSuccess(http.StatusOK, struct {}{})
+	appG.ResponseSuccess(http.StatusOK, struct{}{})
 }
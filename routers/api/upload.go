@@ -46,7 +46,7 @@ func UploadFile(c *gin.Context) {
 
 	fileInformation, err := service.UploadFile(upload.FileType(uploadFileForm.Type), file, fileHeader)
 	if err != nil {
-		logging.Logger.Error("UploadFile error", zap.Error(err))
+		logging.FromContext(c.Request.Context()).Error("UploadFile error", zap.Error(err))
 		appG.Response(http.StatusInternalServerError, errcode.UploadFileError.WithDetails(err.Error()), struct{}{})
 	}
 
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 
 	"gin-example/middleware/session-auth"
 	"gin-example/pkg/app"
@@ -43,7 +44,7 @@ func GetUsers(c *gin.Context) {
 		PageNumber: form.PageNumber,
 		PageSize:   form.PageSize,
 	}
-	usersListResponse, err := users.GetUsers()
+	usersListResponse, err := userssvc.Default().GetUsers(c.Request.Context(), &users)
 	if err != nil {
 		appG.Response(http.StatusInternalServerError, errcode.GetUserError.WithDetails(err.Error()), nil)
 		return
@@ -55,7 +56,6 @@ func GetUsers(c *gin.Context) {
 type AddUsersForm struct {
 	Name     string `form:"name" binding:"required,min=3,max=100"`
 	Password string `form:"password" binding:"required,min=6,max=20"`
-	Role     string `form:"role" binding:"required"`
 	Email    string `form:"email" binding:""`
 	Gender   string `form:"gender" binding:""`
 }
@@ -80,26 +80,21 @@ func Register(c *gin.Context) {
 	user := userssvc.User{
 		Name:     form.Name,
 		Password: password,
-		Role:     form.Role,
 		Email:    form.Email,
 		Gender:   form.Gender,
 	}
-	exists, err := user.ExistByName()
-	if err != nil {
-		appG.Response(http.StatusInternalServerError, errcode.ServerError.WithDetails(err.Error()), struct{}{})
-		return
-	}
-	if exists {
+	err = userssvc.Default().Register(c.Request.Context(), &user)
+	if errors.Is(err, userssvc.ErrNameExists) {
 		appG.Response(http.StatusOK, errcode.CreateUserError.WithDetails("User Name Exist"), struct{}{})
 		return
 	}
-
-	err = user.Add()
 	if err != nil {
 		appG.Response(http.StatusInternalServerError, errcode.ServerError.WithDetails(err.Error()), struct{}{})
 		return
 	}
 
+	// 事务提交后再保存 session, database 存储的 session 不与注册事务争用连接;
+	// 保存失败时用户已创建, 可以直接登陆
 	if err := sessionauth.SaveAuthSession(c, user.ID); err != nil {
 		appG.Response(http.StatusInternalServerError, errcode.CreateSessionError.WithDetails(err.Error()), struct{}{})
 		return
@@ -131,7 +126,7 @@ func Login(c *gin.Context) {
 		Name:     form.Name,
 		Password: form.Password,
 	}
-	if err := user.CheckPassword(); err != nil {
+	if err := userssvc.Default().CheckPassword(c.Request.Context(), &user); err != nil {
 		appG.Response(http.StatusUnauthorized, errcode.UserPasswordError.WithDetails(err.Error()), struct{}{})
 		return
 	}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
 
 	"gin-example/pkg/app"
 	"gin-example/pkg/convert"
@@ -39,14 +40,14 @@ func GetTags(c *gin.Context) {
 		return
 	}
 
-	tagService := tagsvc.Tag{
+	tag := tagsvc.Tag{
 		Name:       query.Name,
 		State:      query.State,
 		PageNumber: query.PageNumber,
 		PageSize:   query.PageSize,
 	}
 
-	tagList, err := tagService.GetTags()
+	tagList, err := tagsvc.Default().GetTags(c.Request.Context(), &tag)
 	if err != nil {
 		appG.Response(http.StatusInternalServerError, errcode.GetTagError.WithDetails(err.Error()), nil)
 		return
@@ -88,22 +89,16 @@ func AddTag(c *gin.Context) {
 		return
 	}
 
-	tagService := tagsvc.Tag{
+	tag := tagsvc.Tag{
 		Name:      form.Name,
 		CreatedBy: form.CreatedBy,
 		State:     form.State,
 	}
-	exists, err := tagService.ExistByName()
-	if err != nil {
-		appG.Response(http.StatusInternalServerError, errcode.ServerError.WithDetails(err.Error()), struct{}{})
-		return
-	}
-	if exists {
+	err := tagsvc.Default().Add(c.Request.Context(), &tag)
+	if errors.Is(err, tagsvc.ErrNameExists) {
 		appG.Response(http.StatusOK, errcode.CreateTagError.WithDetails("Tag Name Exist"), struct{}{})
 		return
 	}
-
-	err = tagService.Add()
 	if err != nil {
 		appG.Response(http.StatusInternalServerError, errcode.ServerError.WithDetails(err.Error()), struct{}{})
 		return
@@ -145,14 +140,14 @@ func EditTag(c *gin.Context) {
 		return
 	}
 
-	tagService := tagsvc.Tag{
+	tag := tagsvc.Tag{
 		ID:         form.ID,
 		Name:       form.Name,
 		ModifiedBy: form.ModifiedBy,
 		State:      form.State,
 	}
 
-	exists, err := tagService.ExistByID()
+	exists, err := tagsvc.Default().ExistByID(c.Request.Context(), &tag)
 	if err != nil {
 		appG.Response(http.StatusInternalServerError, errcode.ServerError.WithDetails(err.Error()), struct{}{})
 		return
@@ -162,7 +157,12 @@ func EditTag(c *gin.Context) {
 		return
 	}
 
-	if err := tagService.Edit(); err != nil {
+	err = tagsvc.Default().Edit(c.Request.Context(), &tag)
+	if errors.Is(err, tagsvc.ErrNameExists) {
+		appG.Response(http.StatusOK, errcode.EditTagError.WithDetails("Tag Name Exist"), struct{}{})
+		return
+	}
+	if err != nil {
 		appG.Response(http.StatusInternalServerError, errcode.EditTagError.WithDetails(err.Error()), struct{}{})
 		return
 	}
@@ -185,8 +185,8 @@ func DeleteTag(c *gin.Context) {
 		return
 	}
 
-	tagService := tagsvc.Tag{ID: id}
-	exists, err := tagService.ExistByID()
+	tag := tagsvc.Tag{ID: id}
+	exists, err := tagsvc.Default().ExistByID(c.Request.Context(), &tag)
 	if err != nil {
 		appG.Response(http.StatusInternalServerError, errcode.ServerError.WithDetails(err.Error()), struct{}{})
 		return
@@ -196,7 +196,7 @@ func DeleteTag(c *gin.Context) {
 		return
 	}
 
-	if err := tagService.Delete(); err != nil {
+	if err := tagsvc.Default().Delete(c.Request.Context(), &tag); err != nil {
 		appG.Response(http.StatusInternalServerError, errcode.DeleteTagError.WithDetails(err.Error()), struct{}{})
 		return
 	}
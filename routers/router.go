@@ -10,18 +10,40 @@ import (
 	"github.com/swaggo/files"       // swagger embed files
 	"github.com/swaggo/gin-swagger" // gin-swagger middleware
 
+	"gin-example/middleware/http-metrics"
+	"gin-example/middleware/request-id"
 	"gin-example/middleware/session-auth"
 	"gin-example/middleware/zaplogger"
 	"gin-example/pkg/logging"
+	"gin-example/pkg/metrics"
 	"gin-example/pkg/setting"
 	"gin-example/routers/api"
 	"gin-example/routers/api/v1"
 )
 
+// NewRouter 按 Session.Store 创建 session 存储并注册全部路由
 func NewRouter() *gin.Engine {
+	return newRouter(sessionauth.EnableCookieSession())
+}
+
+// Routes 返回路由表, session 中间件替换为空实现, 不创建 session 存储, 不连接数据库和 redis
+func Routes() gin.RoutesInfo {
+	return newRouter(func(c *gin.Context) { c.Next() }).Routes()
+}
+
+func newRouter(session gin.HandlerFunc) *gin.Engine {
 	r := gin.New()
 
-	r.Use(zaplogger.Ginzap(logging.Logger))
+	r.Use(requestid.RequestID())
+	r.Use(httpmetrics.Metrics())
+	accessOptions := setting.GetLoggerSetting().AccessOptions
+	r.Use(zaplogger.GinzapWithConfig(logging.AccessLogger, zaplogger.AccessConfig{
+		RedactFields:     accessOptions.RedactFields,
+		CaptureBody:      accessOptions.CaptureBody,
+		MaxBodySize:      accessOptions.MaxBodySize,
+		SampleInitial:    accessOptions.SampleInitial,
+		SampleThereafter: accessOptions.SampleThereafter,
+	}))
 	r.Use(zaplogger.RecoveryWithZap(logging.Logger, true))
 
 	gin.DebugPrintRouteFunc = logging.GinDebugPrintRouteZapLoggerFunc
@@ -39,6 +61,13 @@ func NewRouter() *gin.Engine {
 		"github.com/swaggo/gin-swagger" // gin-swagger middleware
 		_ "i-morefun.net/morefun/go-programming-tour-book/http-service/docs"
 	*/
+	// 存活, 就绪检查
+	r.GET("/healthz", api.Healthz)
+	r.GET("/readyz", api.Readyz)
+
+	// prometheus 指标, 本地 curl http://127.0.0.1:<HttpPort>/metrics 查看
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// jwt auth
@@ -49,9 +78,9 @@ func NewRouter() *gin.Engine {
 
 	// 上传文件
 	r.POST("/upload/file", api.UploadFile)
-	r.StaticFS("/static", http.Dir(setting.AppSetting.UploadSavePath))
+	r.StaticFS("/static", http.Dir(setting.GetAppSetting().UploadSavePath))
 
-	sr := r.Group("/", sessionauth.EnableCookieSession())
+	sr := r.Group("/", session)
 	{
 		// 新建用户
 		sr.POST("/register", api.Register)
@@ -71,6 +100,13 @@ func NewRouter() *gin.Engine {
 				//获取用户列表
 				apiv1.GET("/users", api.GetUsers)
 
+				//当前用户的登录设备
+				apiv1.GET("/sessions", api.GetSessions)
+				//下线其他登录设备
+				apiv1.DELETE("/sessions", api.DeleteOtherSessions)
+				//下线指定登录设备
+				apiv1.DELETE("/sessions/:id", api.DeleteSession)
+
 				//获取标签列表
 				apiv1.GET("/tags", v1.GetTags)
 				//新建标签
@@ -80,6 +116,16 @@ func NewRouter() *gin.Engine {
 				//删除指定标签
 				apiv1.DELETE("/tags/:id", v1.DeleteTag)
 			}
+
+			// 管理接口
+			admin := authorized.Group("/admin", sessionauth.AdminSessionMiddle())
+			{
+				// 查看, 修改日志级别
+				admin.GET("/log/level", api.GetLogLevel)
+				admin.PUT("/log/level", api.SetLogLevel)
+				// 强制下线用户的全部登录设备
+				admin.DELETE("/users/:id/sessions", api.DeleteUserSessions)
+			}
 		}
 	}
 
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 
@@ -9,6 +12,8 @@ import (
 	"gin-example/pkg/errcode"
 )
 
+var authRepository = models.NewAuthRepository()
+
 type Token struct {
 	Token string
 }
@@ -18,9 +23,8 @@ type AuthResponse struct {
 	Data Token
 }
 
-func CheckAuth(appKey, appSecret string) error {
-	auth := models.Auth{AppKey: appKey, AppSecret: appSecret}
-	auth, err := auth.Get()
+func CheckAuth(ctx context.Context, appKey, appSecret string) error {
+	auth, err := authRepository.Get(ctx, appKey, appSecret)
 	if err != nil {
 		return err
 	}
@@ -30,6 +34,28 @@ func CheckAuth(appKey, appSecret string) error {
 	return errors.New("auth info not exist")
 }
 
+// CreateAuth 生成随机的 AppKey, AppSecret 并保存
+func CreateAuth(ctx context.Context) (appKey, appSecret string, err error) {
+	if appKey, err = randomHex(16); err != nil {
+		return "", "", err
+	}
+	if appSecret, err = randomHex(32); err != nil {
+		return "", "", err
+	}
+	if err := authRepository.Create(ctx, &models.Auth{AppKey: appKey, AppSecret: appSecret}); err != nil {
+		return "", "", err
+	}
+	return appKey, appSecret, nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
 func GenerateToken(appKey, appSecret string) (*AuthResponse, error) {
 	token, err := app.GenerateToken(appKey, appSecret)
 	fmt.Println(err)
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gin-example/pkg/errcode"
+	"gin-example/pkg/logging"
+)
+
+// Response struct
+type LogLevelResponse struct {
+	*errcode.ErrorMessage
+	Data []logging.LevelStatus
+}
+
+func GetLogLevels() *LogLevelResponse {
+	return &LogLevelResponse{
+		ErrorMessage: errcode.Success,
+		Data:         logging.Levels(),
+	}
+}
+
+// SetLogLevel 修改日志级别, duration 为空时永久生效, 否则到期后恢复, duration 必须大于 0
+func SetLogLevel(logger, level, duration string) (*LogLevelResponse, error) {
+	var ttl time.Duration
+	if duration != "" {
+		d, err := time.ParseDuration(duration)
+		if err != nil {
+			return nil, err
+		}
+		if d <= 0 {
+			return nil, errors.Errorf("duration must be greater than 0: %s", duration)
+		}
+		ttl = d
+	}
+
+	if err := logging.SetLevel(logger, level, ttl); err != nil {
+		return nil, err
+	}
+	return GetLogLevels(), nil
+}
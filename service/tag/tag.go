@@ -1,10 +1,43 @@
 package tagsvc
 
 import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
 	"gin-example/models"
+	"gin-example/pkg/cache"
+	"gin-example/pkg/database"
 	"gin-example/pkg/errcode"
+	"gin-example/pkg/logging"
 )
 
+// ErrNameExists 标签名已存在
+var ErrNameExists = errors.New("tag name exist")
+
+// Service 标签的业务逻辑, 存储及事务的执行方式由 NewService 传入
+type Service struct {
+	repo        models.TagRepository
+	transaction database.TransactionRunner
+}
+
+// NewService 测试时可以传入 fake 存储及直接执行的 transaction
+func NewService(repo models.TagRepository, transaction database.TransactionRunner) *Service {
+	return &Service{repo: repo, transaction: transaction}
+}
+
+var defaultService = NewService(models.NewTagRepository(), database.Transaction)
+
+// Default 使用数据库存储的标签服务
+func Default() *Service {
+	return defaultService
+}
+
 type Tag struct {
 	ID         int
 	Name       string
@@ -40,40 +73,118 @@ func (t *Tag) getMaps() map[string]interface{} {
 	return maps
 }
 
-func (t *Tag) ExistByName() (bool, error) {
-	return models.ExistTagByName(t.Name)
+// ExistByName 新增前检查, 使用主库避免副本延迟
+func (s *Service) ExistByName(ctx context.Context, t *Tag) (bool, error) {
+	return s.repo.ExistByName(database.WithPrimary(ctx), t.Name)
 }
 
-func (t *Tag) ExistByID() (bool, error) {
-	return models.ExistTagByID(t.ID)
+// ExistByID 编辑, 删除前检查, 使用主库避免副本延迟
+func (s *Service) ExistByID(ctx context.Context, t *Tag) (bool, error) {
+	return s.repo.ExistByID(database.WithPrimary(ctx), t.ID)
 }
 
-func (t *Tag) Add() error {
-	return models.AddTag(t.Name, t.State, t.CreatedBy)
+// Add 检查与新增在同一事务中, 并发新增同名标签时由唯一索引拒绝, 都返回 ErrNameExists
+func (s *Service) Add(ctx context.Context, t *Tag) error {
+	err := s.transaction(ctx, func(ctx context.Context) error {
+		exists, err := s.ExistByName(ctx, t)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrNameExists
+		}
+
+		tag := models.Tag{
+			Name:      t.Name,
+			State:     t.State,
+			CreatedBy: t.CreatedBy,
+		}
+		if err := s.repo.Create(ctx, &tag); err != nil {
+			return err
+		}
+		t.ID = int(tag.ID)
+		return nil
+	})
+	if database.IsDuplicateKey(err) {
+		return ErrNameExists
+	}
+	if err != nil {
+		return err
+	}
+	invalidateTags(ctx)
+	return nil
 }
 
-func (t *Tag) Edit() error {
+func (s *Service) Edit(ctx context.Context, t *Tag) error {
 	data := make(map[string]interface{})
 
 	data["modified_by"] = t.ModifiedBy
 	data["name"] = t.Name
 	data["state"] = t.State
 
-	return models.EditTag(t.ID, data)
+	err := s.repo.Update(ctx, t.ID, data)
+	if database.IsDuplicateKey(err) {
+		return ErrNameExists
+	}
+	if err != nil {
+		return err
+	}
+	invalidateTags(ctx)
+	return nil
+}
+
+func (s *Service) Delete(ctx context.Context, t *Tag) error {
+	if err := s.repo.Delete(ctx, t.ID); err != nil {
+		return err
+	}
+	invalidateTags(ctx)
+	return nil
+}
+
+// 标签列表的缓存 key 包含版本号, 新增, 编辑, 删除后删除版本号使全部列表缓存失效, 旧的列表缓存等待过期
+const tagsVersionKey = "tags:version"
+
+func tagsVersion(ctx context.Context) (string, error) {
+	var version string
+	err := cache.Default().GetOrLoad(ctx, tagsVersionKey, &version, 0, func(ctx context.Context) (interface{}, error) {
+		return strconv.FormatInt(time.Now().UnixNano(), 36), nil
+	})
+	return version, err
+}
+
+// invalidateTags 数据已修改成功, 缓存删除失败时只记录日志, 列表缓存在过期后更新
+func invalidateTags(ctx context.Context) {
+	if err := cache.Default().Delete(ctx, tagsVersionKey); err != nil {
+		logging.FromContext(ctx).Warn("invalidate tags cache failed", zap.Error(err))
+	}
 }
 
-func (t *Tag) Delete() error {
-	return models.DeleteTag(t.ID)
+// GetTags 按查询条件缓存列表及总数, 缓存未命中通常发生在修改之后, 从主库加载避免缓存副本延迟的旧数据
+func (s *Service) GetTags(ctx context.Context, t *Tag) (*TagList, error) {
+	version, err := tagsVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("tags:%s:list:%d:%d:%s:%d", version, t.PageNumber, t.PageSize, url.QueryEscape(t.Name), t.State)
+
+	var tagList TagList
+	err = cache.Default().GetOrLoad(ctx, key, &tagList, 0, func(ctx context.Context) (interface{}, error) {
+		return s.getTags(database.WithPrimary(ctx), t)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &tagList, nil
 }
 
-func (t *Tag) GetTags() (*TagList, error) {
-	tags, err := models.GetTags(t.PageNumber, t.PageSize, t.getMaps())
+func (s *Service) getTags(ctx context.Context, t *Tag) (*TagList, error) {
+	tags, err := s.repo.List(ctx, t.PageNumber, t.PageSize, t.getMaps())
 	if err != nil {
 		return nil, err
 	}
 	tagList := &TagList{Tags: tags}
 
-	count, err := models.GetTagTotal(t.getMaps())
+	count, err := s.repo.Count(ctx, t.getMaps())
 	if err != nil {
 		return nil, err
 	}
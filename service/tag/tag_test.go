@@ -0,0 +1,233 @@
+package tagsvc
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"gin-example/models"
+	"gin-example/pkg/cache"
+	"gin-example/pkg/logging"
+)
+
+// fakeTagRepository 内存中的 TagRepository, 只支持 name, state 条件
+type fakeTagRepository struct {
+	tags   map[int]models.Tag
+	nextID int
+	lists  int
+	// duplicateOnCreate 模拟并发新增同名标签时由唯一索引拒绝
+	duplicateOnCreate bool
+}
+
+// errDuplicate 唯一索引冲突, 使用 mysql 的错误使测试不依赖 cgo
+var errDuplicate = &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+
+func newFakeTagRepository() *fakeTagRepository {
+	return &fakeTagRepository{tags: make(map[int]models.Tag)}
+}
+
+func (f *fakeTagRepository) match(tag models.Tag, maps map[string]interface{}) bool {
+	if name, ok := maps["name"]; ok && tag.Name != name {
+		return false
+	}
+	if state, ok := maps["state"]; ok && tag.State != state {
+		return false
+	}
+	return true
+}
+
+func (f *fakeTagRepository) Count(ctx context.Context, maps map[string]interface{}) (uint, error) {
+	var count uint
+	for _, tag := range f.tags {
+		if f.match(tag, maps) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeTagRepository) ExistByName(ctx context.Context, name string) (bool, error) {
+	for _, tag := range f.tags {
+		if tag.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeTagRepository) ExistByID(ctx context.Context, id int) (bool, error) {
+	_, ok := f.tags[id]
+	return ok, nil
+}
+
+func (f *fakeTagRepository) Create(ctx context.Context, tag *models.Tag) error {
+	if f.duplicateOnCreate {
+		return errDuplicate
+	}
+	f.nextID++
+	tag.ID = uint(f.nextID)
+	f.tags[f.nextID] = *tag
+	return nil
+}
+
+func (f *fakeTagRepository) Update(ctx context.Context, id int, data map[string]interface{}) error {
+	tag, ok := f.tags[id]
+	if !ok {
+		return nil
+	}
+	for otherID, other := range f.tags {
+		if otherID != id && other.Name == data["name"] {
+			return errDuplicate
+		}
+	}
+	tag.Name = data["name"].(string)
+	tag.State = data["state"].(int)
+	tag.ModifiedBy = data["modified_by"].(string)
+	f.tags[id] = tag
+	return nil
+}
+
+func (f *fakeTagRepository) Delete(ctx context.Context, id int) error {
+	delete(f.tags, id)
+	return nil
+}
+
+func (f *fakeTagRepository) List(ctx context.Context, pageNumber, pageSize int, maps interface{}) ([]models.Tag, error) {
+	f.lists++
+	tags := make([]models.Tag, 0, len(f.tags))
+	for _, tag := range f.tags {
+		if f.match(tag, maps.(map[string]interface{})) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].ID < tags[j].ID })
+	return tags, nil
+}
+
+// newTestService 使用 fake 存储的服务, fake 存储不支持事务, 直接执行
+func newTestService(t *testing.T, repo models.TagRepository) *Service {
+	t.Helper()
+	logging.Logger = zap.NewNop()
+	cache.SetDefault(cache.New(cache.NewMemoryStore(100), "", time.Minute))
+	return NewService(repo, func(ctx context.Context, fn func(ctx context.Context) error) error {
+		return fn(ctx)
+	})
+}
+
+func TestAdd(t *testing.T) {
+	repo := newFakeTagRepository()
+	svc := newTestService(t, repo)
+	ctx := context.Background()
+
+	tag := &Tag{Name: "go", CreatedBy: "alice", State: 1}
+	if err := svc.Add(ctx, tag); err != nil {
+		t.Fatal(err)
+	}
+	if tag.ID == 0 || repo.tags[tag.ID].CreatedBy != "alice" {
+		t.Fatalf("tag %+v not created", tag)
+	}
+
+	// 检查时已存在
+	if err := svc.Add(ctx, &Tag{Name: "go", State: 1}); !errors.Is(err, ErrNameExists) {
+		t.Fatalf("add existing tag: want ErrNameExists, got %v", err)
+	}
+
+	// 检查通过后被唯一索引拒绝
+	repo.duplicateOnCreate = true
+	if err := svc.Add(ctx, &Tag{Name: "rust", State: 1}); !errors.Is(err, ErrNameExists) {
+		t.Fatalf("add tag rejected by unique index: want ErrNameExists, got %v", err)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	repo := newFakeTagRepository()
+	svc := newTestService(t, repo)
+	ctx := context.Background()
+
+	for _, name := range []string{"go", "rust"} {
+		if err := svc.Add(ctx, &Tag{Name: name, State: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := svc.Edit(ctx, &Tag{ID: 1, Name: "golang", State: 0, ModifiedBy: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if tag := repo.tags[1]; tag.Name != "golang" || tag.State != 0 || tag.ModifiedBy != "bob" {
+		t.Fatalf("tag after Edit = %+v", tag)
+	}
+
+	if err := svc.Edit(ctx, &Tag{ID: 1, Name: "rust", State: 1}); !errors.Is(err, ErrNameExists) {
+		t.Fatalf("rename to existing tag: want ErrNameExists, got %v", err)
+	}
+}
+
+// 列表缓存在新增, 编辑, 删除后失效
+func TestGetTagsCache(t *testing.T) {
+	repo := newFakeTagRepository()
+	svc := newTestService(t, repo)
+	ctx := context.Background()
+
+	if err := svc.Add(ctx, &Tag{Name: "go", State: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	query := &Tag{State: 1, PageSize: 10}
+	getTags := func() *TagList {
+		t.Helper()
+		tagList, err := svc.GetTags(ctx, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tagList
+	}
+
+	if tagList := getTags(); tagList.TotalCount != 1 || len(tagList.Tags) != 1 {
+		t.Fatalf("GetTags = %+v, want 1 tag", tagList)
+	}
+	getTags()
+	if repo.lists != 1 {
+		t.Fatalf("repository List called %d times, want 1 (cached)", repo.lists)
+	}
+
+	if err := svc.Add(ctx, &Tag{Name: "rust", State: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if tagList := getTags(); tagList.TotalCount != 2 {
+		t.Fatalf("GetTags after Add = %+v, want 2 tags", tagList)
+	}
+
+	if err := svc.Delete(ctx, &Tag{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if tagList := getTags(); tagList.TotalCount != 1 || tagList.Tags[0].Name != "rust" {
+		t.Fatalf("GetTags after Delete = %+v, want rust only", tagList)
+	}
+	if repo.lists != 3 {
+		t.Fatalf("repository List called %d times, want 3", repo.lists)
+	}
+}
+
+// 存储返回的错误原样返回
+func TestGetTagsError(t *testing.T) {
+	svc := newTestService(t, errTagRepository{newFakeTagRepository()})
+
+	if _, err := svc.GetTags(context.Background(), &Tag{State: -1}); !errors.Is(err, errList) {
+		t.Fatalf("GetTags: want %v, got %v", errList, err)
+	}
+}
+
+var errList = errors.New("list failed")
+
+type errTagRepository struct {
+	*fakeTagRepository
+}
+
+func (errTagRepository) List(ctx context.Context, pageNumber, pageSize int, maps interface{}) ([]models.Tag, error) {
+	return nil, errList
+}
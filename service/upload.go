@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"gin-example/pkg/errcode"
+	"gin-example/pkg/metrics"
 	"gin-example/pkg/upload"
 )
 
@@ -50,6 +51,7 @@ func UploadFile(fileType upload.FileType, multipartFile multipart.File, multipar
 	if err := upload.StorageFile(multipartFileHeader, storageDestination); err != nil {
 		return nil, err
 	}
+	metrics.UploadBytes.Add(float64(multipartFileHeader.Size))
 
 	return &FileInformation{Name: encryptionName, AccessUrl: strings.Join([]string{"/static", encryptionName}, "/")}, nil
 }
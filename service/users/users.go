@@ -1,11 +1,39 @@
 package userssvc
 
 import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
 	"gin-example/models"
 	"gin-example/pkg/app"
+	"gin-example/pkg/cache"
+	"gin-example/pkg/database"
 	"gin-example/pkg/errcode"
 )
 
+// ErrNameExists 用户名已存在
+var ErrNameExists = errors.New("user name exist")
+
+// Service 用户的业务逻辑, 存储及事务的执行方式由 NewService 传入
+type Service struct {
+	repo        models.UserRepository
+	transaction database.TransactionRunner
+}
+
+// NewService 测试时可以传入 fake 存储及直接执行的 transaction
+func NewService(repo models.UserRepository, transaction database.TransactionRunner) *Service {
+	return &Service{repo: repo, transaction: transaction}
+}
+
+var defaultService = NewService(models.NewUserRepository(), database.Transaction)
+
+// Default 使用数据库存储的用户服务
+func Default() *Service {
+	return defaultService
+}
+
 type User struct {
 	ID       uint
 	Name     string
@@ -51,14 +79,14 @@ func (u *User) getMaps() map[string]interface{} {
 	return maps
 }
 
-func (u *User) GetUsers() (*UsersListResponse, error) {
-	users, err := models.GetUsers(u.PageNumber, u.PageSize, u.getMaps())
+func (s *Service) GetUsers(ctx context.Context, u *User) (*UsersListResponse, error) {
+	users, err := s.repo.List(ctx, u.PageNumber, u.PageSize, u.getMaps())
 	if err != nil {
 		return nil, err
 	}
 	usersList := &UsersList{Users: users}
 
-	count, err := models.GetUsersTotal(u.getMaps())
+	count, err := s.repo.Count(ctx, u.getMaps())
 	if err != nil {
 		return nil, err
 	}
@@ -70,16 +98,49 @@ func (u *User) GetUsers() (*UsersListResponse, error) {
 	}, nil
 }
 
-func (u *User) ExistByName() (bool, error) {
-	return models.UserExistByName(u.Name)
+// ExistByName 注册前检查, 使用主库避免副本延迟
+func (s *Service) ExistByName(ctx context.Context, u *User) (bool, error) {
+	return s.repo.ExistByName(database.WithPrimary(ctx), u.Name)
 }
 
-func (u *User) Add() error {
-	return models.AddUser(u.Name, u.Password, u.Role, u.Email, u.Gender)
+// Add 检查与新增在同一事务中, 并发注册同名用户时由唯一索引拒绝, 都返回 ErrNameExists
+func (s *Service) Add(ctx context.Context, u *User) error {
+	err := s.transaction(ctx, func(ctx context.Context) error {
+		exists, err := s.ExistByName(ctx, u)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrNameExists
+		}
+
+		user := models.User{
+			Name:     u.Name,
+			Password: u.Password,
+			Role:     u.Role,
+			Email:    u.Email,
+			Gender:   u.Gender,
+		}
+		if err := s.repo.Create(ctx, &user); err != nil {
+			return err
+		}
+		u.ID = user.ID
+		return nil
+	})
+	if database.IsDuplicateKey(err) {
+		return ErrNameExists
+	}
+	return err
 }
 
-func (u *User) CheckPassword() error {
-	id, password, err := models.GetUserPasswordByName(u.Name)
+// Register 注册的用户固定为普通用户, 检查与新增在同一事务中, 见 Add
+func (s *Service) Register(ctx context.Context, u *User) error {
+	u.Role = models.RoleUser
+	return s.Add(ctx, u)
+}
+
+func (s *Service) CheckPassword(ctx context.Context, u *User) error {
+	id, password, err := s.repo.GetPasswordByName(ctx, u.Name)
 	if err != nil {
 		return err
 	}
@@ -92,3 +153,38 @@ func (u *User) CheckPassword() error {
 
 	return nil
 }
+
+// ResetPassword 按用户名修改密码, u.Password 为未加密的密码
+func (s *Service) ResetPassword(ctx context.Context, u *User) error {
+	password, err := app.Encrypt(u.Password)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdatePasswordByName(ctx, u.Name, password)
+}
+
+// Detail 按 ID 查询用户, 结果缓存, 不包含密码; 缓存的角色可能过期, 鉴权使用 Role
+func (s *Service) Detail(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	err := cache.Default().GetOrLoad(ctx, fmt.Sprintf("user:%d", id), &user, 0, func(ctx context.Context) (interface{}, error) {
+		user, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		user.Password = ""
+		return user, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Role 按 ID 查询用户的角色, 不使用缓存, 直接修改数据库授予或撤销的角色立即生效
+func (s *Service) Role(ctx context.Context, id uint) (string, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return user.Role, nil
+}
@@ -0,0 +1,236 @@
+package userssvc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"gin-example/models"
+	"gin-example/pkg/app"
+	"gin-example/pkg/cache"
+)
+
+// fakeUserRepository 内存中的 UserRepository, 以用户名为 key
+type fakeUserRepository struct {
+	users  map[string]models.User
+	nextID uint
+}
+
+func (f *fakeUserRepository) List(ctx context.Context, pageNumber, pageSize int, maps interface{}) ([]models.User, error) {
+	users := make([]models.User, 0, len(f.users))
+	for _, user := range f.users {
+		user.Password = ""
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (f *fakeUserRepository) Count(ctx context.Context, maps map[string]interface{}) (uint, error) {
+	return uint(len(f.users)), nil
+}
+
+func (f *fakeUserRepository) ExistByName(ctx context.Context, name string) (bool, error) {
+	_, ok := f.users[name]
+	return ok, nil
+}
+
+func (f *fakeUserRepository) GetByName(ctx context.Context, name string) (*models.User, error) {
+	user, ok := f.users[name]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &user, nil
+}
+
+func (f *fakeUserRepository) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	for _, user := range f.users {
+		if user.ID == id {
+			return &user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeUserRepository) GetPasswordByName(ctx context.Context, name string) (uint, string, error) {
+	user, ok := f.users[name]
+	if !ok {
+		return 0, "", gorm.ErrRecordNotFound
+	}
+	return user.ID, user.Password, nil
+}
+
+func (f *fakeUserRepository) Create(ctx context.Context, user *models.User) error {
+	f.nextID++
+	user.ID = f.nextID
+	f.users[user.Name] = *user
+	return nil
+}
+
+func (f *fakeUserRepository) UpdatePasswordByName(ctx context.Context, name, password string) error {
+	user, ok := f.users[name]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	user.Password = password
+	f.users[name] = user
+	return nil
+}
+
+// newTestService 使用 fake 存储的服务, fake 存储不支持事务, 直接执行
+func newTestService(t *testing.T) (*Service, *fakeUserRepository) {
+	t.Helper()
+	repo := &fakeUserRepository{users: make(map[string]models.User)}
+	return NewService(repo, func(ctx context.Context, fn func(ctx context.Context) error) error {
+		return fn(ctx)
+	}), repo
+}
+
+func TestAdd(t *testing.T) {
+	svc, repo := newTestService(t)
+	ctx := context.Background()
+
+	user := &User{Name: "alice", Password: "hashed", Role: models.RoleAdmin}
+	if err := svc.Add(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == 0 || repo.users["alice"].Role != models.RoleAdmin {
+		t.Fatalf("user %+v not created", user)
+	}
+	if err := svc.Add(ctx, &User{Name: "alice", Password: "hashed"}); !errors.Is(err, ErrNameExists) {
+		t.Fatalf("add existing user: want ErrNameExists, got %v", err)
+	}
+}
+
+// 注册接口不能指定角色
+func TestRegisterAsUser(t *testing.T) {
+	svc, repo := newTestService(t)
+	ctx := context.Background()
+
+	user := &User{Name: "mallory", Password: "hashed", Role: models.RoleAdmin}
+	if err := svc.Register(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if role := repo.users["mallory"].Role; role != models.RoleUser {
+		t.Fatalf("registered role = %q, want %q", role, models.RoleUser)
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+
+	hashed, err := app.Encrypt("secret1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Add(ctx, &User{Name: "alice", Password: hashed, Role: models.RoleUser}); err != nil {
+		t.Fatal(err)
+	}
+
+	user := &User{Name: "alice", Password: "secret1"}
+	if err := svc.CheckPassword(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == 0 {
+		t.Fatal("user.ID is not set by CheckPassword")
+	}
+	if err := svc.CheckPassword(ctx, &User{Name: "alice", Password: "wrong"}); err == nil {
+		t.Fatal("check wrong password: want error, got nil")
+	}
+	if err := svc.CheckPassword(ctx, &User{Name: "bob", Password: "secret1"}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("check missing user: want gorm.ErrRecordNotFound, got %v", err)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	svc, repo := newTestService(t)
+	ctx := context.Background()
+
+	if err := svc.Add(ctx, &User{Name: "alice", Password: "old", Role: models.RoleUser}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.ResetPassword(ctx, &User{Name: "alice", Password: "secret2"}); err != nil {
+		t.Fatal(err)
+	}
+	// 保存加密后的密码
+	if err := app.Compare(repo.users["alice"].Password, "secret2"); err != nil {
+		t.Fatalf("stored password does not match: %v", err)
+	}
+	if err := svc.ResetPassword(ctx, &User{Name: "bob", Password: "secret2"}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("reset missing user: want gorm.ErrRecordNotFound, got %v", err)
+	}
+}
+
+func TestDetail(t *testing.T) {
+	svc, _ := newTestService(t)
+	cache.SetDefault(cache.New(cache.NewMemoryStore(100), "", 0))
+	ctx := context.Background()
+
+	user := &User{Name: "alice", Password: "hashed", Role: models.RoleUser, Email: "alice@example.com"}
+	if err := svc.Add(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	detail, err := svc.Detail(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.Name != "alice" || detail.Email != "alice@example.com" || detail.Password != "" {
+		t.Fatalf("Detail = %+v, want alice without password", detail)
+	}
+	if _, err := svc.Detail(ctx, user.ID+1); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Detail of missing user: want gorm.ErrRecordNotFound, got %v", err)
+	}
+}
+
+func TestGetUsers(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"alice", "bob"} {
+		if err := svc.Add(ctx, &User{Name: name, Password: "hashed", Role: models.RoleUser}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := svc.GetUsers(ctx, &User{PageSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data.TotalCount != 2 || len(resp.Data.Users) != 2 {
+		t.Fatalf("GetUsers = %+v, want 2 users", resp.Data)
+	}
+	for _, user := range resp.Data.Users {
+		if user.Password != "" {
+			t.Fatalf("GetUsers returns password of %s", user.Name)
+		}
+	}
+}
+
+// 鉴权使用的角色不经过缓存, 直接修改数据库后立即生效
+func TestRoleIsNotCached(t *testing.T) {
+	svc, repo := newTestService(t)
+	cache.SetDefault(cache.New(cache.NewMemoryStore(100), "", 0))
+	ctx := context.Background()
+
+	user := &User{Name: "alice", Password: "hashed", Role: models.RoleUser}
+	if err := svc.Add(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Detail(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	admin := repo.users["alice"]
+	admin.Role = models.RoleAdmin
+	repo.users["alice"] = admin
+
+	role, err := svc.Role(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role != models.RoleAdmin {
+		t.Fatalf("Role = %q, want %q", role, models.RoleAdmin)
+	}
+}